@@ -0,0 +1,400 @@
+package router
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// errRequiredParam is returned when the required key is missing
+var errRequiredParam = errors.New("required")
+
+// maxMultipartMemory is the memory limit for parsing multipart form, rest are stored in temporary files
+const maxMultipartMemory = 32 << 20
+
+// defaultMaxBodyBytes is the default of Router.MaxBodyBytes
+const defaultMaxBodyBytes = 10 << 20
+
+// requestBinder builds the handler arg from the request
+type requestBinder func(req *http.Request) (reflect.Value, error)
+
+// hasTaggedFields reports whether t is struct or pointer to struct, which has the fields tagged by tag
+func hasTaggedFields(t reflect.Type, tag string) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newQueryBinder returns the binder filling the fields tagged `query:"name"` from the query string.
+// untagged fields are not bound.
+func newQueryBinder(t reflect.Type, lookup converterLookup) (requestBinder, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	st := t
+	if isPtr {
+		st = t.Elem()
+	}
+	fields, err := taggedFieldBinders(st, "query", lookup)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) (reflect.Value, error) {
+		v := reflect.New(st)
+		if err := bindFields(v.Elem(), fields, req.URL.Query()); err != nil {
+			return reflect.Value{}, err
+		}
+		if isPtr {
+			return v, nil
+		}
+		return v.Elem(), nil
+	}, nil
+}
+
+// pathBinder builds the handler arg from the path parameters
+type pathBinder func(ps PathParams) (reflect.Value, error)
+
+// newPathBinder returns the binder filling the fields tagged `path:"name"` by the path parameter names.
+// the fields of the typed params are converted by the declared types, see validateSegmentType.
+// returns error when the tagged name is not in names, or the struct also has the fields tagged by query or form,
+// those are never bound and should be split into another arg.
+func newPathBinder(t reflect.Type, names, types []string, lookup converterLookup, registered registeredLookup) (pathBinder, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	st := t
+	if isPtr {
+		st = t.Elem()
+	}
+	for _, tag := range []string{"query", "form"} {
+		if hasTaggedFields(st, tag) {
+			return nil, errors.Wrapf(ErrInvalidHandler, "path struct is must not have the fields tagged by %s. got:%v", tag, st)
+		}
+	}
+	fields, err := taggedFieldBinders(st, "path", lookup)
+	if err != nil {
+		return nil, err
+	}
+	// index of the path parameter for each field
+	pos := make([]int, len(fields))
+	for i, f := range fields {
+		if f.slice {
+			return nil, errors.Wrapf(ErrInvalidHandler, "unsupported field type. field=%s, got:%v", st.Field(f.index).Name, st.Field(f.index).Type)
+		}
+		pos[i] = -1
+		for j, name := range names {
+			if name == f.name {
+				pos[i] = j
+				break
+			}
+		}
+		if pos[i] < 0 {
+			return nil, errors.Wrapf(ErrInvalidHandler, "not found path param. field=%s, param=%s, path params=%v", st.Field(f.index).Name, f.name, names)
+		}
+		if pos[i] < len(types) && len(types[pos[i]]) != 0 {
+			ft := st.Field(f.index).Type
+			if err := validateSegmentType(types[pos[i]], ft, registered); err != nil {
+				return nil, errors.Wrapf(err, "field=%s", st.Field(f.index).Name)
+			}
+			fields[i].conv, _ = segmentConverter(ft)
+		}
+	}
+	return func(ps PathParams) (reflect.Value, error) {
+		v := reflect.New(st)
+		for i, f := range fields {
+			if pos[i] >= len(ps) {
+				return reflect.Value{}, &ParamError{Name: f.name, Err: errRequiredParam}
+			}
+			raw := ps[pos[i]].Value
+			x, err := f.conv(raw)
+			if err != nil {
+				return reflect.Value{}, &ParamError{Name: f.name, Raw: raw, Err: err}
+			}
+			v.Elem().Field(f.index).Set(x)
+		}
+		if isPtr {
+			return v, nil
+		}
+		return v.Elem(), nil
+	}, nil
+}
+
+// isBodyType reports whether t is bound to the request body.
+// t is struct or pointer to struct, which has the fields tagged by json, xml or form, or implemented ValidationBody.
+// the types convertible as the path parameter should be excluded before.
+func isBodyType(t reflect.Type) bool {
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return false
+	}
+	if reflect.PtrTo(st).Implements(validationBodyType) {
+		return true
+	}
+	for _, tag := range []string{"json", "xml", "form"} {
+		if hasTaggedFields(st, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// newBodyBinder returns the binder decoding the request body into t by the Content-Type.
+//
+//	application/json, */*+json: encoding/json
+//	application/xml, text/xml, */*+xml: encoding/xml
+//	application/x-www-form-urlencoded, multipart/form-data: fields tagged `form:"name"`
+//
+// decoded value is validated when implemented ValidationBody.
+func newBodyBinder(t reflect.Type, lookup converterLookup) (requestBinder, error) {
+	isPtr := t.Kind() == reflect.Ptr
+	st := t
+	if isPtr {
+		st = t.Elem()
+	}
+	fields, err := newFieldBinders(st, "form", lookup)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) (reflect.Value, error) {
+		v := reflect.New(st)
+		ct, err := decodeBody(req, v, fields)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if vb, ok := v.Interface().(ValidationBody); ok {
+			if err := vb.ValidateBody(); err != nil {
+				return reflect.Value{}, &BodyError{ContentType: ct, Err: &validationError{err: err}}
+			}
+		}
+		if isPtr {
+			return v, nil
+		}
+		return v.Elem(), nil
+	}, nil
+}
+
+// decodeBody decodes the request body into v, returns the media type of the body
+func decodeBody(req *http.Request, v reflect.Value, fields []fieldBinder) (string, error) {
+	raw := req.Header.Get("Content-Type")
+	ct, _, err := mime.ParseMediaType(raw)
+	if err != nil {
+		return "", errors.Wrapf(ErrUnsupportedMediaType, "content-type=%s", raw)
+	}
+
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		if err := json.NewDecoder(req.Body).Decode(v.Interface()); err != nil {
+			return ct, &BodyError{ContentType: ct, Err: err}
+		}
+	case ct == "application/xml" || ct == "text/xml" || strings.HasSuffix(ct, "+xml"):
+		if err := xml.NewDecoder(req.Body).Decode(v.Interface()); err != nil {
+			return ct, &BodyError{ContentType: ct, Err: err}
+		}
+	case ct == "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return ct, &BodyError{ContentType: ct, Err: err}
+		}
+		if err := bindFields(v.Elem(), fields, req.PostForm); err != nil {
+			return ct, &BodyError{ContentType: ct, Err: err}
+		}
+	case ct == "multipart/form-data":
+		if err := req.ParseMultipartForm(maxMultipartMemory); err != nil {
+			return ct, &BodyError{ContentType: ct, Err: err}
+		}
+		if err := bindFields(v.Elem(), fields, req.MultipartForm.Value); err != nil {
+			return ct, &BodyError{ContentType: ct, Err: err}
+		}
+	default:
+		return ct, errors.Wrapf(ErrUnsupportedMediaType, "content-type=%s", raw)
+	}
+	return ct, nil
+}
+
+// limitedBody is the request body returns ErrBodyTooLarge when read over n bytes
+type limitedBody struct {
+	io.ReadCloser
+	// remaining bytes
+	n int64
+}
+
+func newLimitedBody(rc io.ReadCloser, n int64) *limitedBody {
+	return &limitedBody{ReadCloser: rc, n: n}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// read one more byte to detect exceeding
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.n {
+		n = int(b.n)
+		b.n = -1
+		return n, ErrBodyTooLarge
+	}
+	b.n -= int64(n)
+	return n, err
+}
+
+// fieldBinder sets the values of the key to the struct field
+type fieldBinder struct {
+	index    int
+	name     string
+	required bool
+	// raw value used when the key is missing
+	def   string
+	isDef bool
+	// converts each value when the field is slice, otherwise the first value
+	conv  Converter
+	slice bool
+}
+
+// newFieldBinders returns the binders of the exported fields of the struct t by the tag.
+//
+//	`form:"name"`: bound from the key "name", the field name is used when not tagged
+//	`form:"name,required"`: missing key is the error
+//	`form:"-"`: skipped
+//	`default:"1"`: used when the key is missing
+//
+// slice fields receive the all values of the key, and pointer fields are nil when the key is missing.
+//
+// returns error when the tagged field type is not supported, the others are skipped.
+func newFieldBinders(t reflect.Type, tag string, lookup converterLookup) ([]fieldBinder, error) {
+	fields := []fieldBinder{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tagged, opts := parseTag(f.Tag.Get(tag))
+		if tagged == "-" {
+			continue
+		}
+		fb := fieldBinder{index: i, name: tagged, required: opts["required"]}
+		if fb.name == "" {
+			fb.name = f.Name
+		}
+		fb.def, fb.isDef = f.Tag.Lookup("default")
+
+		conv, ok := lookup(f.Type)
+		switch {
+		case ok:
+		case f.Type.Kind() == reflect.Slice:
+			conv, ok = lookup(f.Type.Elem())
+			fb.slice = true
+		case f.Type.Kind() == reflect.Ptr:
+			conv, ok = lookup(f.Type.Elem())
+			conv = ptrConverter(f.Type.Elem(), conv)
+		}
+		if !ok {
+			if tagged != "" {
+				return nil, errors.Wrapf(ErrInvalidHandler, "unsupported field type. field=%s, got:%v", f.Name, f.Type)
+			}
+			continue
+		}
+		fb.conv = conv
+		if fb.isDef {
+			if _, err := conv(fb.def); err != nil {
+				return nil, errors.Wrapf(ErrInvalidHandler, "invalid default value. field=%s, default=%s, error=%v", f.Name, fb.def, err)
+			}
+		}
+		fields = append(fields, fb)
+	}
+	return fields, nil
+}
+
+// ptrConverter returns the converter to the pointer of t, the value is converted via conv
+func ptrConverter(t reflect.Type, conv Converter) Converter {
+	if conv == nil {
+		return nil
+	}
+	return func(raw string) (reflect.Value, error) {
+		v, err := conv(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t)
+		p.Elem().Set(v)
+		return p, nil
+	}
+}
+
+// taggedFieldBinders is like newFieldBinders but returns the binders of the tagged fields only
+func taggedFieldBinders(t reflect.Type, tag string, lookup converterLookup) ([]fieldBinder, error) {
+	all, err := newFieldBinders(t, tag, lookup)
+	if err != nil {
+		return nil, err
+	}
+	fields := []fieldBinder{}
+	for _, f := range all {
+		if _, ok := t.Field(f.index).Tag.Lookup(tag); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// parseTag returns the name and the options of the tag, e.g. "page,required"
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := map[string]bool{}
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
+
+// bindFields sets the values to the struct v via the binders
+func bindFields(v reflect.Value, fields []fieldBinder, values url.Values) error {
+	for _, f := range fields {
+		vs := values[f.name]
+		if len(vs) == 0 {
+			switch {
+			case f.isDef:
+				vs = []string{f.def}
+			case f.required:
+				return &ParamError{Name: f.name, Err: errRequiredParam}
+			default:
+				continue
+			}
+		}
+
+		fv := v.Field(f.index)
+		if !f.slice {
+			x, err := f.conv(vs[0])
+			if err != nil {
+				return &ParamError{Name: f.name, Raw: vs[0], Err: err}
+			}
+			fv.Set(x)
+			continue
+		}
+		s := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, raw := range vs {
+			x, err := f.conv(raw)
+			if err != nil {
+				return &ParamError{Name: f.name, Raw: raw, Err: err}
+			}
+			s.Index(i).Set(x)
+		}
+		fv.Set(s)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type dummyBody struct {
+	Name string   `json:"name" xml:"name" form:"name"`
+	Age  int      `json:"age" xml:"age" form:"age"`
+	Tags []string `json:"tags" xml:"tag" form:"tag"`
+}
+
+func (b *dummyBody) ValidateBody() error {
+	if b.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type repoPath struct {
+	OrgID  int    `path:"org"`
+	RepoID string `path:"repo"`
+}
+
+func multipartBody(t *testing.T, values map[string]string) (string, string) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	for k, v := range values {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatalf("want no error, got %v", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	return buf.String(), mw.FormDataContentType()
+}
+
+func TestServeHTTPWithBody(t *testing.T) {
+	r := NewRouter()
+	r.MaxBodyBytes = 512
+	r.Post("/user/:id", func(w http.ResponseWriter, req *http.Request, id int, body *dummyBody) {
+		fmt.Fprintf(w, "id=%d name=%s age=%d tags=%v", id, body.Name, body.Age, body.Tags)
+	})
+	r.Put("/user/:id", func(body dummyBody, id int) (interface{}, error) {
+		return map[string]interface{}{"id": id, "name": body.Name}, nil
+	})
+
+	mp, mpType := multipartBody(t, map[string]string{"name": "foo", "age": "20"})
+	largeMp, largeMpType := multipartBody(t, map[string]string{"name": strings.Repeat("a", 512)})
+	cases := []struct {
+		inputMethod string
+		inputType   string
+		inputBody   string
+		expectCode  int
+		expectBody  string
+	}{
+		{"POST", "application/json", `{"name":"foo","age":20,"tags":["a","b"]}`, 200, "id=1 name=foo age=20 tags=[a b]"},
+		{"POST", "application/vnd.api+json; charset=utf-8", `{"name":"foo"}`, 200, "id=1 name=foo age=0 tags=[]"},
+		{"POST", "application/xml", `<dummyBody><name>foo</name><tag>a</tag></dummyBody>`, 200, "id=1 name=foo age=0 tags=[a]"},
+		{"POST", "application/x-www-form-urlencoded", "name=foo&age=20&tag=a&tag=b", 200, "id=1 name=foo age=20 tags=[a b]"},
+		{"POST", mpType, mp, 200, "id=1 name=foo age=20 tags=[]"},
+		{"PUT", "application/json", `{"name":"foo"}`, 200, `{"id":1,"name":"foo"}`},
+		{"POST", "application/json", `{"name":`, 400, "invalid body\n"},
+		{"POST", "application/json", `{"age":20}`, 400, "invalid body: name is required\n"},
+		{"POST", "application/x-www-form-urlencoded", "name=foo&age=bar", 400, "invalid body\n"},
+		{"POST", "application/json", `{"name":"` + strings.Repeat("a", 512) + `"}`, 413, "Request Entity Too Large\n"},
+		{"POST", largeMpType, largeMp, 413, "Request Entity Too Large\n"},
+		{"POST", "text/plain", "name=foo", 415, "Unsupported Media Type\n"},
+		{"POST", "", "name=foo", 415, "Unsupported Media Type\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.inputMethod, "/user/1", strings.NewReader(c.inputBody))
+		req.Header.Set("Content-Type", c.inputType)
+		r.ServeHTTP(w, req)
+		if w.Code != c.expectCode {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectCode, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestIsBodyType(t *testing.T) {
+	type untagged struct {
+		Name string
+	}
+	type jsonTagged struct {
+		Name string `json:"name"`
+	}
+	type xmlTagged struct {
+		Name string `xml:"name"`
+	}
+	type formTagged struct {
+		Name string `form:"name"`
+	}
+
+	cases := []struct {
+		input  interface{}
+		expect bool
+	}{
+		{dummyBody{}, true},
+		{&dummyBody{}, true},
+		{jsonTagged{}, true},
+		{&xmlTagged{}, true},
+		{formTagged{}, true},
+		{untagged{}, false},
+		{&untagged{}, false},
+		{struct{}{}, false},
+		{"", false},
+		{[]dummyBody{}, false},
+	}
+	for i, c := range cases {
+		if got := isBodyType(reflect.TypeOf(c.input)); got != c.expect {
+			t.Errorf("#%d: want %t, got %t", i, c.expect, got)
+		}
+	}
+}
+
+func TestServeHTTPWithUntaggedStruct(t *testing.T) {
+	type untagged struct {
+		Name string
+	}
+
+	r := NewRouter()
+	if _, err := r.Handle("POST", "/user", func(w http.ResponseWriter, body untagged) {}); errors.Cause(err) != ErrInvalidHandler {
+		t.Errorf("want error:%v, got error:%v", ErrInvalidHandler, err)
+	}
+	if _, err := r.Handle("POST", "/user", func(w http.ResponseWriter, body *dummyBody) {
+		fmt.Fprintf(w, "name=%s", body.Name)
+	}); err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/user", strings.NewReader(`{"name":"foo"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "name=foo" {
+		t.Errorf("want 200 and body:%q, got %d and body:%q", "name=foo", w.Code, w.Body.String())
+	}
+}
+
+func TestServeHTTPWithQuery(t *testing.T) {
+	type search struct {
+		Q     string   `query:"q,required"`
+		Page  int      `query:"page" default:"1"`
+		Tags  []string `query:"tag"`
+		Limit *int     `query:"limit"`
+		Other string
+	}
+
+	r := NewRouter()
+	r.Get("/user/:id/posts", func(w http.ResponseWriter, id int, s *search) {
+		limit := -1
+		if s.Limit != nil {
+			limit = *s.Limit
+		}
+		fmt.Fprintf(w, "id=%d q=%s page=%d tags=%v limit=%d other=%s", id, s.Q, s.Page, s.Tags, limit, s.Other)
+	})
+	r.Get("/search", func(s search) (interface{}, error) {
+		return map[string]interface{}{"q": s.Q, "page": s.Page}, nil
+	})
+
+	cases := []struct {
+		inputPath  string
+		expectCode int
+		expectBody string
+	}{
+		{"/user/1/posts?q=foo&page=2&tag=a&tag=b&limit=10&Other=x", 200, "id=1 q=foo page=2 tags=[a b] limit=10 other="},
+		{"/user/1/posts?q=foo", 200, "id=1 q=foo page=1 tags=[] limit=-1 other="},
+		{"/search?q=foo&page=3", 200, `{"page":3,"q":"foo"}`},
+		{"/user/1/posts?page=2", 400, "invalid param: q: required\n"},
+		{"/user/1/posts?q=foo&page=bar", 400, "invalid param: page\n"},
+		{"/user/1/posts?q=foo&limit=bar", 400, "invalid param: limit\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.inputPath, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != c.expectCode {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectCode, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestNewFieldBinders(t *testing.T) {
+	cases := []struct {
+		input  interface{}
+		expect error
+	}{
+		{struct {
+			A int      `query:"a" default:"1"`
+			B []string `query:"b,required"`
+			C struct{}
+		}{}, nil},
+		{struct {
+			A struct{} `query:"a"`
+		}{}, ErrInvalidHandler},
+		{struct {
+			A int `query:"a" default:"foo"`
+		}{}, ErrInvalidHandler},
+	}
+	for i, c := range cases {
+		_, err := newFieldBinders(reflect.TypeOf(c.input), "query", builtinConverter)
+		if errors.Cause(err) != c.expect {
+			t.Errorf("#%d: want error:%v, got error:%v", i, c.expect, err)
+		}
+	}
+}
+
+func TestBindFields(t *testing.T) {
+	type form struct {
+		Name    string
+		Age     int    `form:"age" default:"18"`
+		IDs     []int  `form:"id"`
+		Email   string `form:"email,required"`
+		Ignored string `form:"-"`
+		Nested  struct{}
+		private string
+	}
+
+	cases := []struct {
+		input       url.Values
+		expect      form
+		expectError error
+	}{
+		{
+			url.Values{"Name": {"foo"}, "age": {"20"}, "id": {"1", "2"}, "email": {"a@b"}, "Ignored": {"x"}, "-": {"x"}, "Nested": {"x"}, "private": {"x"}},
+			form{Name: "foo", Age: 20, IDs: []int{1, 2}, Email: "a@b"},
+			nil,
+		},
+		{
+			url.Values{"email": {"a@b"}},
+			form{Age: 18, Email: "a@b"},
+			nil,
+		},
+		{
+			url.Values{},
+			form{},
+			ErrInvalidParam,
+		},
+		{
+			url.Values{"age": {"foo"}, "email": {"a@b"}},
+			form{},
+			ErrInvalidParam,
+		},
+		{
+			url.Values{"id": {"1", "foo"}, "email": {"a@b"}},
+			form{},
+			ErrInvalidParam,
+		},
+	}
+	fields, err := newFieldBinders(reflect.TypeOf(form{}), "form", builtinConverter)
+	if err != nil {
+		t.Fatalf("want no error, got %v", err)
+	}
+	for i, c := range cases {
+		var result form
+		err := bindFields(reflect.ValueOf(&result).Elem(), fields, c.input)
+		if errors.Cause(err) != c.expectError {
+			t.Errorf("#%d: want error:%v, got error:%v", i, c.expectError, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(result, c.expect) {
+			t.Errorf("#%d: want:%#v, got:%#v", i, c.expect, result)
+		}
+	}
+}
+
+func TestServeHTTPWithPathStruct(t *testing.T) {
+	r := NewRouter()
+	r.Get("/orgs/:org/repos/:repo", func(w http.ResponseWriter, p *repoPath) {
+		fmt.Fprintf(w, "org=%d repo=%s", p.OrgID, p.RepoID)
+	})
+	r.Get("/orgs/:org/repos/:repo/issues/:number", func(p repoPath, q struct {
+		State string `query:"state" default:"open"`
+	}) (interface{}, error) {
+		return map[string]interface{}{"org": p.OrgID, "repo": p.RepoID, "state": q.State}, nil
+	})
+
+	cases := []struct {
+		inputPath  string
+		expectCode int
+		expectBody string
+	}{
+		{"/orgs/1/repos/foo", 200, "org=1 repo=foo"},
+		{"/orgs/1/repos/foo/issues/10", 200, `{"org":1,"repo":"foo","state":"open"}`},
+		{"/orgs/bar/repos/foo", 400, "invalid param: org\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if w.Code != c.expectCode {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectCode, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"net/http"
+	"sync"
+)
+
+type contextKey int
+
+const (
+	routeContextKey contextKey = iota
+	originalPathContextKey
+)
+
+// PathParam is a path parameter matched by the route pattern
+type PathParam struct {
+	// parameter name in the pattern. e.g. "id" of "/user/:id"
+	Key   string
+	Value string
+}
+
+// PathParams is the path parameters in order of the pattern
+type PathParams []PathParam
+
+// ByName returns the value of the first parameter named name, returns empty string when not found
+func (ps PathParams) ByName(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// pathParamsPool reuses the buffers of the path parameters between requests
+var pathParamsPool = sync.Pool{
+	New: func() interface{} {
+		ps := make(PathParams, 0, paramsCap)
+		return &ps
+	},
+}
+
+func getPathParams() *PathParams {
+	ps := pathParamsPool.Get().(*PathParams)
+	*ps = (*ps)[:0]
+	return ps
+}
+
+func putPathParams(ps *PathParams) {
+	// release the references to the request path
+	for i := range *ps {
+		(*ps)[i] = PathParam{}
+	}
+	pathParamsPool.Put(ps)
+}
+
+// routeMatch is the result of routing, stored in the request context
+type routeMatch struct {
+	route   *Route
+	pattern string
+	params  PathParams
+}
+
+// values returns the values of ps for HandlerData.params
+func (ps PathParams) values() []interface{} {
+	vs := make([]interface{}, len(ps))
+	for i, p := range ps {
+		vs[i] = p.Value
+	}
+	return vs
+}
+
+func currentMatch(req *http.Request) *routeMatch {
+	m, _ := req.Context().Value(routeContextKey).(*routeMatch)
+	return m
+}
+
+// CurrentRoute returns the matched route of the request.
+// returns nil when called outside the handlers and middlewares of the router.
+func CurrentRoute(req *http.Request) *Route {
+	if m := currentMatch(req); m != nil {
+		return m.route
+	}
+	return nil
+}
+
+// Param returns the value of the path parameter named name, e.g. "id" of "/user/:id".
+// returns empty string when not matched.
+func Param(req *http.Request, name string) string {
+	return Params(req).ByName(name)
+}
+
+// Params returns the path parameters of the request in order of the pattern.
+// returns nil when not matched or the pattern has no parameters.
+// the returned PathParams is reused after the handler returned, should be copied when retained.
+func Params(req *http.Request) PathParams {
+	if m := currentMatch(req); m != nil {
+		return m.params
+	}
+	return nil
+}
+
+// RoutePattern returns the pattern of the matched route, e.g. "/user/:id".
+// returns empty string when not matched.
+func RoutePattern(req *http.Request) string {
+	if m := currentMatch(req); m != nil {
+		return m.pattern
+	}
+	return ""
+}
+
+// OriginalPath returns the request path before stripped the prefix by Router.Mount
+func OriginalPath(req *http.Request) string {
+	if path, ok := req.Context().Value(originalPathContextKey).(string); ok {
+		return path
+	}
+	return req.URL.Path
+}
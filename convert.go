@@ -0,0 +1,242 @@
+package router
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// errFailedValidation is returned when ValidationParam.Validate returns false
+var errFailedValidation = errors.New("failed to validation")
+
+// validationError is the reason of failure returned from the validation interfaces, responded to the client
+type validationError struct {
+	err error
+}
+
+func (e *validationError) Error() string { return e.err.Error() }
+
+// Unwrap returns the error returned from the validation
+func (e *validationError) Unwrap() error { return e.err }
+
+// Converter converts the raw path parameter to the value of the handler arg type
+type Converter func(raw string) (reflect.Value, error)
+
+// converterLookup returns the Converter for the type, returns false when not supported
+type converterLookup func(t reflect.Type) (Converter, bool)
+
+// registeredLookup reports whether the Converter for the type is registered via RegisterConverter
+type registeredLookup func(t reflect.Type) bool
+
+// RegisterConverter register the Converter for the handler args of the type t.
+// registered Converter is used in preference to the builtin conversions,
+// and applied to the routes registered after.
+func (r *Router) RegisterConverter(t reflect.Type, conv Converter) {
+	if r.converters == nil {
+		r.converters = make(map[reflect.Type]Converter)
+	}
+	r.converters[t] = conv
+}
+
+// registered reports whether the Converter for t is registered
+func (r *Router) registered(t reflect.Type) bool {
+	_, ok := r.converters[t]
+	return ok
+}
+
+// converter returns the registered Converter or the builtin Converter for t
+func (r *Router) converter(t reflect.Type) (Converter, bool) {
+	if conv, ok := r.converters[t]; ok {
+		return func(raw string) (reflect.Value, error) {
+			v, err := conv(raw)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if !v.IsValid() || !v.Type().AssignableTo(t) {
+				return reflect.Value{}, errors.Errorf("converter returned mismatched type. want %v, got %v", t, v)
+			}
+			return v, nil
+		}, true
+	}
+	return builtinConverter(t)
+}
+
+// segmentLookup returns the lookup converting by the declared built-in type typ via segmentConverter.
+// returns lookup when typ is not the built-in type.
+func segmentLookup(typ string, lookup converterLookup) converterLookup {
+	if _, ok := segmentTypes[typ]; !ok {
+		return lookup
+	}
+	return segmentConverter
+}
+
+// segmentConverter returns the converter for the typed param.
+// t is validated via validateSegmentType before, returns false for the other kinds.
+func segmentConverter(t reflect.Type) (Converter, bool) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return func(raw string) (reflect.Value, error) {
+			n, err := strconv.ParseInt(raw, 10, t.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetInt(n)
+			return v, nil
+		}, true
+	case reflect.Float64:
+		return func(raw string) (reflect.Value, error) {
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetFloat(f)
+			return v, nil
+		}, true
+	case reflect.String:
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t).Elem()
+			v.SetString(raw)
+			return v, nil
+		}, true
+	}
+	return nil, false
+}
+
+// builtinConverter returns the converter for t. returns false when t is not supported.
+//
+// supported types in priority:
+//
+//	types implemented ParamValidator, either value or pointer
+//	types implemented ValidationParam, either value or pointer
+//	types implemented encoding.TextUnmarshaler, either value or pointer
+//	time.Duration
+//	int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64
+//	float32, float64, bool
+//	string and named string types
+//	types assignable from string, e.g. interface{}
+func builtinConverter(t reflect.Type) (Converter, bool) {
+	switch {
+	case t.Kind() == reflect.Ptr && t.Implements(paramValidatorType):
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t.Elem())
+			if err := v.Interface().(ParamValidator).ValidateParam(raw); err != nil {
+				return reflect.Value{}, &validationError{err: err}
+			}
+			return v, nil
+		}, true
+	case reflect.PtrTo(t).Implements(paramValidatorType):
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t)
+			if err := v.Interface().(ParamValidator).ValidateParam(raw); err != nil {
+				return reflect.Value{}, &validationError{err: err}
+			}
+			return v.Elem(), nil
+		}, true
+	case t.Kind() == reflect.Ptr && t.Implements(validationParamType):
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t.Elem())
+			if !v.Interface().(ValidationParam).Validate(raw) {
+				return reflect.Value{}, errFailedValidation
+			}
+			return v, nil
+		}, true
+	case reflect.PtrTo(t).Implements(validationParamType):
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t)
+			if !v.Interface().(ValidationParam).Validate(raw) {
+				return reflect.Value{}, errFailedValidation
+			}
+			return v.Elem(), nil
+		}, true
+	case t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerType):
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t.Elem())
+			if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+				return reflect.Value{}, err
+			}
+			return v, nil
+		}, true
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t)
+			if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+				return reflect.Value{}, err
+			}
+			return v.Elem(), nil
+		}, true
+	case t == durationType:
+		return func(raw string) (reflect.Value, error) {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(d), nil
+		}, true
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(raw string) (reflect.Value, error) {
+			n, err := strconv.ParseInt(raw, 10, t.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetInt(n)
+			return v, nil
+		}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(raw string) (reflect.Value, error) {
+			n, err := strconv.ParseUint(raw, 10, t.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetUint(n)
+			return v, nil
+		}, true
+	case reflect.Float32, reflect.Float64:
+		return func(raw string) (reflect.Value, error) {
+			f, err := strconv.ParseFloat(raw, t.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetFloat(f)
+			return v, nil
+		}, true
+	case reflect.Bool:
+		return func(raw string) (reflect.Value, error) {
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v := reflect.New(t).Elem()
+			v.SetBool(b)
+			return v, nil
+		}, true
+	case reflect.String:
+		return func(raw string) (reflect.Value, error) {
+			v := reflect.New(t).Elem()
+			v.SetString(raw)
+			return v, nil
+		}, true
+	}
+
+	if stringType.AssignableTo(t) {
+		return func(raw string) (reflect.Value, error) {
+			return reflect.ValueOf(raw), nil
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,196 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type dummyNamedString string
+
+type dummyTextParam struct {
+	value string
+}
+
+func (p *dummyTextParam) UnmarshalText(b []byte) error {
+	p.value = "text:" + string(b)
+	return nil
+}
+
+type dummyParamValidator struct {
+	id int
+}
+
+func (v *dummyParamValidator) ValidateParam(raw string) error {
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return errors.New("id must be positive number")
+	}
+	v.id = id
+	return nil
+}
+
+func TestBuiltinConverter(t *testing.T) {
+	cases := []struct {
+		inputType   reflect.Type
+		inputRaw    string
+		expect      interface{}
+		expectError bool
+	}{
+		{reflect.TypeOf(int(0)), "-10", int(-10), false},
+		{reflect.TypeOf(int8(0)), "127", int8(127), false},
+		{reflect.TypeOf(int8(0)), "128", nil, true},
+		{reflect.TypeOf(int16(0)), "-300", int16(-300), false},
+		{reflect.TypeOf(int32(0)), "10", int32(10), false},
+		{reflect.TypeOf(int64(0)), "10", int64(10), false},
+		{reflect.TypeOf(int64(0)), "foo", nil, true},
+		{reflect.TypeOf(uint(0)), "10", uint(10), false},
+		{reflect.TypeOf(uint8(0)), "255", uint8(255), false},
+		{reflect.TypeOf(uint16(0)), "-1", nil, true},
+		{reflect.TypeOf(uint32(0)), "10", uint32(10), false},
+		{reflect.TypeOf(uint64(0)), "10", uint64(10), false},
+		{reflect.TypeOf(float32(0)), "1.5", float32(1.5), false},
+		{reflect.TypeOf(float64(0)), "1.25", float64(1.25), false},
+		{reflect.TypeOf(float64(0)), "foo", nil, true},
+		{reflect.TypeOf(true), "true", true, false},
+		{reflect.TypeOf(true), "0", false, false},
+		{reflect.TypeOf(true), "foo", nil, true},
+		{reflect.TypeOf(time.Duration(0)), "1m30s", 90 * time.Second, false},
+		{reflect.TypeOf(time.Duration(0)), "10", nil, true},
+		{reflect.TypeOf(""), "foo", "foo", false},
+		{reflect.TypeOf(dummyNamedString("")), "foo", dummyNamedString("foo"), false},
+		{reflect.TypeOf((*interface{})(nil)).Elem(), "foo", "foo", false},
+		{reflect.TypeOf(dummyTextParam{}), "foo", dummyTextParam{value: "text:foo"}, false},
+		{reflect.TypeOf(&dummyTextParam{}), "foo", &dummyTextParam{value: "text:foo"}, false},
+		{reflect.TypeOf(net.IP{}), "127.0.0.1", net.ParseIP("127.0.0.1"), false},
+		{reflect.TypeOf(net.IP{}), "foo", nil, true},
+		{reflect.TypeOf(&dummyValidationParam{}), "100", &dummyValidationParam{raw: "100"}, false},
+		{reflect.TypeOf(&dummyValidationParam{}), "1000", nil, true},
+		{reflect.TypeOf(dummyValidationParam{}), "100", dummyValidationParam{raw: "100"}, false},
+		{reflect.TypeOf(dummyValidationParam{}), "1000", nil, true},
+		{reflect.TypeOf(&dummyParamValidator{}), "10", &dummyParamValidator{id: 10}, false},
+		{reflect.TypeOf(&dummyParamValidator{}), "-1", nil, true},
+		{reflect.TypeOf(dummyParamValidator{}), "10", dummyParamValidator{id: 10}, false},
+		{reflect.TypeOf(dummyParamValidator{}), "foo", nil, true},
+	}
+	for i, c := range cases {
+		conv, ok := builtinConverter(c.inputType)
+		if !ok {
+			t.Fatalf("#%d: want supported type %v", i, c.inputType)
+		}
+		v, err := conv(c.inputRaw)
+		if (err != nil) != c.expectError {
+			t.Errorf("#%d: want error:%t, got error:%v", i, c.expectError, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !v.Type().AssignableTo(c.inputType) {
+			t.Errorf("#%d: want type:%v, got type:%v", i, c.inputType, v.Type())
+		}
+		if !reflect.DeepEqual(v.Interface(), c.expect) {
+			t.Errorf("#%d: want:%#v, got:%#v", i, c.expect, v.Interface())
+		}
+	}
+}
+
+func TestBuiltinConverterUnsupported(t *testing.T) {
+	cases := []reflect.Type{
+		reflect.TypeOf(struct{}{}),
+		reflect.TypeOf([]int{}),
+		reflect.TypeOf(map[string]string{}),
+		reflect.TypeOf(complex64(0)),
+		reflect.TypeOf(new(int)),
+	}
+	for i, c := range cases {
+		if _, ok := builtinConverter(c); ok {
+			t.Errorf("#%d: want unsupported type %v", i, c)
+		}
+	}
+}
+
+type dummyUserID struct {
+	id int
+}
+
+func TestRegisterConverter(t *testing.T) {
+	r := NewRouter()
+	// unregistered type is rejected on registration
+	if _, err := r.Handle("GET", "/user/:id", func(w http.ResponseWriter, req *http.Request, id dummyUserID) {}); errors.Cause(err) != ErrInvalidHandler {
+		t.Fatalf("want error:%v, got error:%v", ErrInvalidHandler, err)
+	}
+
+	r.RegisterConverter(reflect.TypeOf(dummyUserID{}), func(raw string) (reflect.Value, error) {
+		if !strings.HasPrefix(raw, "u") {
+			return reflect.Value{}, errors.New("want prefix 'u'")
+		}
+		id, err := strconv.Atoi(raw[1:])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(dummyUserID{id: id}), nil
+	})
+	// preferred to the builtin conversion
+	r.RegisterConverter(reflect.TypeOf(""), func(raw string) (reflect.Value, error) {
+		return reflect.ValueOf(strings.ToUpper(raw)), nil
+	})
+	r.RegisterConverter(reflect.TypeOf(true), func(raw string) (reflect.Value, error) {
+		return reflect.ValueOf(raw), nil
+	})
+	r.Get("/user/:id/:name", func(w http.ResponseWriter, req *http.Request, id dummyUserID, name string) {
+		fmt.Fprintf(w, "id=%d, name=%s", id.id, name)
+	})
+	r.Get("/mismatch/:b", func(w http.ResponseWriter, req *http.Request, b bool) {})
+
+	// typed param of the registered type is rejected on registration
+	type pathStruct struct {
+		Name string `path:"name"`
+	}
+	typedCases := []func() error{
+		func() error {
+			_, err := r.Handle("GET", "/typed/a/:name:alpha", func(w http.ResponseWriter, req *http.Request, name string) {})
+			return err
+		},
+		func() error {
+			_, err := r.Handle("GET", "/typed/b/:name:alpha", func(w http.ResponseWriter, req *http.Request, p pathStruct) {})
+			return err
+		},
+		func() error {
+			_, err := Handle1(r, "GET", "/typed/c/:name:alpha", func(w http.ResponseWriter, req *http.Request, name string) {})
+			return err
+		},
+	}
+	for i, c := range typedCases {
+		if err := c(); errors.Cause(err) != ErrInvalidHandler {
+			t.Errorf("#%d: want error:%v, got error:%v", i, ErrInvalidHandler, err)
+		}
+	}
+
+	cases := []struct {
+		inputPath    string
+		expectStatus int
+		expectBody   string
+	}{
+		{"/user/u10/foo", 200, "id=10, name=FOO"},
+		{"/user/10/foo", 400, "invalid param: id\n"},
+		{"/mismatch/true", 400, "invalid param: b\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps http.Handler to add the cross-cutting behavior to handlers
+type Middleware func(http.Handler) http.Handler
+
+// Group is represents routes sharing the path prefix and middlewares
+type Group struct {
+	router      *Router
+	parent      *Group
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group creates the route group with the path prefix.
+// fn is called with the created group when not nil.
+func (r *Router) Group(prefix string, fn func(g *Group)) *Group {
+	g := &Group{
+		router: r,
+		prefix: joinPath("", prefix),
+	}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+// Group creates the nested route group inherited the prefix and middlewares
+func (g *Group) Group(prefix string, fn func(g *Group)) *Group {
+	child := &Group{
+		router: g.router,
+		parent: g,
+		prefix: joinPath(g.prefix, prefix),
+	}
+	if fn != nil {
+		fn(child)
+	}
+	return child
+}
+
+// Use appends middlewares applied to the routes in the group and the nested groups
+func (g *Group) Use(mw ...Middleware) {
+	g.middlewares = append(g.middlewares, mw...)
+}
+
+// Get register handler via GET
+func (g *Group) Get(path string, h baseHandler) *Route { return g.HandleFunc("GET", path, h) }
+
+// Head register handler via HEAD
+func (g *Group) Head(path string, h baseHandler) *Route { return g.HandleFunc("HEAD", path, h) }
+
+// Post register handler via POST
+func (g *Group) Post(path string, h baseHandler) *Route { return g.HandleFunc("POST", path, h) }
+
+// Put register handler via PUT
+func (g *Group) Put(path string, h baseHandler) *Route { return g.HandleFunc("PUT", path, h) }
+
+// Patch register handler via PATCH
+func (g *Group) Patch(path string, h baseHandler) *Route { return g.HandleFunc("PATCH", path, h) }
+
+// Delete register handler via DELETE
+func (g *Group) Delete(path string, h baseHandler) *Route { return g.HandleFunc("DELETE", path, h) }
+
+// Options register handler via OPTIONS
+func (g *Group) Options(path string, h baseHandler) *Route { return g.HandleFunc("OPTIONS", path, h) }
+
+// HandleFunc register handler each HTTP method with the group prefix
+func (g *Group) HandleFunc(method, path string, h baseHandler) *Route {
+	route := g.router.HandleFunc(method, joinPath(g.prefix, path), h)
+	route.group = g
+	return route
+}
+
+// Handle register handler each HTTP method with the group prefix, see Router.Handle
+func (g *Group) Handle(method, path string, h baseHandler) (*Route, error) {
+	route, err := g.router.Handle(method, joinPath(g.prefix, path), h)
+	if err != nil {
+		return nil, err
+	}
+	route.group = g
+	return route, nil
+}
+
+// MustHandle is like Handle but panics when failed to register
+func (g *Group) MustHandle(method, path string, h baseHandler) *Route {
+	route, err := g.Handle(method, path, h)
+	if err != nil {
+		panic(err)
+	}
+	return route
+}
+
+// ServeDir register handler for static directories
+func (g *Group) ServeDir(path string, root http.FileSystem) {
+	g.Get(path, serveDirHandler(root))
+}
+
+// ServeFile register handler for static files
+func (g *Group) ServeFile(path string, file string) {
+	g.Get(path, serveFileHandler(file))
+}
+
+// Mount register h for the all requests under the prefix in the group
+func (g *Group) Mount(prefix string, h http.Handler) {
+	prefix = joinPath("", prefix)
+	exact, sub := mountHandlers(joinPath(g.prefix, prefix), h)
+	for _, method := range mountMethods {
+		g.HandleFunc(method, prefix, exact)
+		g.HandleFunc(method, joinPath(prefix, "/*path"), sub)
+	}
+}
+
+// joinPath joins the group prefix and the path
+// e.g. joinPath("/api/", "/user") => "/api/user", joinPath("/api", "/") => "/api"
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if len(path) != 0 && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if path == "/" && len(prefix) != 0 {
+		path = ""
+	}
+	if p := prefix + path; len(p) != 0 {
+		return p
+	}
+	return "/"
+}
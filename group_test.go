@@ -0,0 +1,86 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func appendHeaderMiddleware(value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("X-Middleware", value)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	r := NewRouter()
+	r.Get("/", dummyHandler)
+	r.Group("/api/", func(api *Group) {
+		api.Use(appendHeaderMiddleware("api"))
+		api.Get("/", dummyHandler)
+		api.Group("/v1", func(v1 *Group) {
+			v1.Use(appendHeaderMiddleware("v1"))
+			v1.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {
+				fmt.Fprintf(w, "id=%d", id)
+			})
+			v1.Post("user", dummyHandler)
+		})
+		api.ServeFile("/foo", "./testdata/foo")
+	})
+	g := r.Group("/admin", nil)
+	g.Use(appendHeaderMiddleware("admin"))
+	g.Delete("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+
+	cases := []struct {
+		inputMethod      string
+		inputPath        string
+		expectStatus     int
+		expectBody       string
+		expectMiddleware []string
+	}{
+		{"GET", "/", 200, "hello, world", nil},
+		{"GET", "/api", 200, "hello, world", []string{"api"}},
+		{"GET", "/api/v1/user/10", 200, "id=10", []string{"api", "v1"}},
+		{"POST", "/api/v1/user", 200, "hello, world", []string{"api", "v1"}},
+		{"GET", "/api/foo", 200, "hello from testdata/foo\n", []string{"api"}},
+		{"DELETE", "/admin/user/10", 200, "", []string{"admin"}},
+		{"GET", "/v1/user/10", 404, "404 page not found\n", nil},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.inputMethod, c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+		if got := w.Header()["X-Middleware"]; fmt.Sprint(got) != fmt.Sprint(c.expectMiddleware) {
+			t.Errorf("#%d: want middlewares:%v, got middlewares:%v", i, c.expectMiddleware, got)
+		}
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	cases := []struct {
+		prefix string
+		path   string
+		expect string
+	}{
+		{"", "/", "/"},
+		{"", "", "/"},
+		{"/api", "/", "/api"},
+		{"/api/", "/user", "/api/user"},
+		{"/api", "user/:id", "/api/user/:id"},
+		{"/api", "", "/api"},
+	}
+	for i, c := range cases {
+		if result := joinPath(c.prefix, c.path); result != c.expect {
+			t.Errorf("#%d: want:%s, got:%s", i, c.expect, result)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	responseWriterType  = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	requestType         = reflect.TypeOf((*http.Request)(nil))
+	contextType         = reflect.TypeOf((*context.Context)(nil)).Elem()
+	headerType          = reflect.TypeOf(http.Header{})
+	valuesType          = reflect.TypeOf(url.Values{})
+	pathParamsType      = reflect.TypeOf(PathParams{})
+	validationParamType = reflect.TypeOf((*ValidationParam)(nil)).Elem()
+	paramValidatorType  = reflect.TypeOf((*ParamValidator)(nil)).Elem()
+	validationBodyType  = reflect.TypeOf((*ValidationBody)(nil)).Elem()
+	stringType          = reflect.TypeOf("")
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// validateHandler checks that the handler signature is callable with the path parameters.
+//
+// handler args are matched by type in any position, and the others are the path parameters in order.
+// the handler does not need to start with (http.ResponseWriter, *http.Request), and may omit either.
+//
+//	http.ResponseWriter
+//	*http.Request
+//	context.Context: req.Context()
+//	http.Header: req.Header
+//	url.Values: req.URL.Query()
+//	PathParams: Params(req)
+//	struct or pointer to struct has fields tagged `path:"name"`: bound from the path parameters by name
+//	struct or pointer to struct has fields tagged `query:"name"`: bound from the query string
+//	struct or pointer to struct has fields tagged by json, xml or form, or implemented ValidationBody: decoded request body
+//
+// the path parameters are either bound by PathParams, the struct tagged `path:"name"` or passed in order.
+//
+// handler results are allowed nothing, error, or (value, error). e.g.
+//
+//	func(w http.ResponseWriter, req *http.Request, id int)
+//	func(ctx context.Context, id int) (*User, error)
+func validateHandler(path string, h baseHandler, lookup converterLookup, registered registeredLookup) error {
+	t := reflect.TypeOf(h)
+	if t == nil || t.Kind() != reflect.Func {
+		return errors.Wrapf(ErrInvalidHandler, "handler is must be Func. got:%T", h)
+	}
+	if t.IsVariadic() {
+		return errors.Wrapf(ErrInvalidHandler, "handler is must not be variadic. got:%v", t)
+	}
+	if _, ok := handlerResults(t); !ok {
+		return errors.Wrapf(ErrInvalidHandler, "handler results are must be nothing, error or (value, error). got:%v", t)
+	}
+
+	names, err := paramNames(path)
+	if err != nil {
+		return err
+	}
+	types, err := paramSegmentTypes(path)
+	if err != nil {
+		return err
+	}
+
+	paramTypes := []reflect.Type{}
+	seen := map[argKind]bool{}
+	for i := 0; i < t.NumIn(); i++ {
+		kind, ok := requestArg(t.In(i), lookup)
+		if !ok {
+			paramTypes = append(paramTypes, t.In(i))
+			continue
+		}
+		if seen[kind] {
+			return errors.Wrapf(ErrInvalidHandler, "duplicated arg %v. got:%v", t.In(i), t)
+		}
+		seen[kind] = true
+		if kind == argPathStruct {
+			if _, err := newPathBinder(t.In(i), names, types, lookup, registered); err != nil {
+				return errors.Wrapf(err, "got:%v", t)
+			}
+			continue
+		}
+		if _, err := newRequestBinder(kind, t.In(i), lookup); err != nil {
+			return errors.Wrapf(err, "got:%v", t)
+		}
+	}
+
+	if seen[argPathStruct] || seen[argPathParams] {
+		if len(paramTypes) != 0 {
+			return errors.Wrapf(ErrInvalidHandler, "params are must be either the path struct or in order. got:%v", t)
+		}
+		return nil
+	}
+	if len(paramTypes) != len(names) {
+		return errors.Wrapf(ErrInvalidHandler, "number of params mismatch. path has %d params %v, handler has %d params. got:%v", len(names), names, len(paramTypes), t)
+	}
+	for i, name := range names {
+		if err := validateSegmentType(types[i], paramTypes[i], registered); err != nil {
+			return errors.Wrapf(err, "param=%s", name)
+		}
+		if err := validateParamType(paramTypes[i], segmentLookup(types[i], lookup)); err != nil {
+			return errors.Wrapf(err, "param=%s", name)
+		}
+	}
+	return nil
+}
+
+func validateParamType(t reflect.Type, lookup converterLookup) error {
+	if _, ok := lookup(t); !ok {
+		return errors.Wrapf(ErrInvalidHandler, "unsupported param type. got:%v", t)
+	}
+	return nil
+}
+
+// paramNames returns the names of ":param" and "*wildcard" in the path
+// e.g. "/user/:id<[0-9]+>/*filepath" => ["id", "filepath"]
+func paramNames(path string) ([]string, error) {
+	parts, err := generateSplitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, p := range parts[1:] {
+		if len(p) != 0 && (isParamKey(p) || isWildcardKey(p)) {
+			names = append(names, segmentName(p))
+		}
+	}
+	return names, nil
+}
+
+// handlerPlan is the analyzed handler signature, built once on registration.
+// args are decoded via the plan without inspecting the handler type per request.
+type handlerPlan struct {
+	fn reflect.Value
+	// fast path for func(http.ResponseWriter, *http.Request), called without reflection
+	direct func(http.ResponseWriter, *http.Request)
+	// fast path for the handlers of HandlerFunc and the generic adapters e.g. Get1, called without reflection
+	typed typedHandler
+	// how to build each arg
+	in        []argPlan
+	numParams int
+	// the path parameters are bound by name instead of numParams in order
+	bindsPath bool
+	// has the arg decoded from the request body, limited by Router.MaxBodyBytes
+	hasBody  bool
+	out      resultKind
+	argsPool sync.Pool
+}
+
+type argKind int
+
+const (
+	argParam argKind = iota
+	argResponseWriter
+	argRequest
+	argContext
+	argHeader
+	argQuery
+	argPathParams
+	argPathStruct
+	argQueryStruct
+	argBody
+)
+
+type argPlan struct {
+	kind argKind
+	// decode the path parameter for argParam
+	decode paramDecoder
+	// build the arg from the request for argQueryStruct and argBody
+	bind requestBinder
+	// build the arg from the path parameters for argPathStruct
+	bindPath pathBinder
+}
+
+type resultKind int
+
+const (
+	resultNone resultKind = iota
+	resultError
+	resultValueError
+)
+
+type paramDecoder func(raw string) (reflect.Value, error)
+
+// typedHandler calls the handler with the path parameters, the params are decoded without reflection
+type typedHandler func(w http.ResponseWriter, req *http.Request, ps PathParams) error
+
+// injectedArg returns the kind of arg built from the request, returns false for the path parameters
+func injectedArg(t reflect.Type) (argKind, bool) {
+	switch t {
+	case responseWriterType:
+		return argResponseWriter, true
+	case requestType:
+		return argRequest, true
+	case contextType:
+		return argContext, true
+	case headerType:
+		return argHeader, true
+	case valuesType:
+		return argQuery, true
+	case pathParamsType:
+		return argPathParams, true
+	}
+	return argParam, false
+}
+
+// requestArg returns the kind of arg built from the request including the body, returns false for the path parameters
+func requestArg(t reflect.Type, lookup converterLookup) (argKind, bool) {
+	if kind, ok := injectedArg(t); ok {
+		return kind, true
+	}
+	if _, ok := lookup(t); ok {
+		return argParam, false
+	}
+	if hasTaggedFields(t, "path") {
+		return argPathStruct, true
+	}
+	if hasTaggedFields(t, "query") {
+		return argQueryStruct, true
+	}
+	if isBodyType(t) {
+		return argBody, true
+	}
+	return argParam, false
+}
+
+// newRequestBinder returns the binder for argQueryStruct and argBody, returns nil for the others
+func newRequestBinder(kind argKind, t reflect.Type, lookup converterLookup) (requestBinder, error) {
+	switch kind {
+	case argQueryStruct:
+		return newQueryBinder(t, lookup)
+	case argBody:
+		return newBodyBinder(t, lookup)
+	}
+	return nil, nil
+}
+
+func handlerResults(t reflect.Type) (resultKind, bool) {
+	switch t.NumOut() {
+	case 0:
+		return resultNone, true
+	case 1:
+		return resultError, t.Out(0) == errorType
+	case 2:
+		return resultValueError, t.Out(1) == errorType
+	}
+	return resultNone, false
+}
+
+// newHandlerPlan returns the plan of the handler.
+// h is must be Func, names are the path parameter names used for error messages,
+// and types are the declared built-in types of the path parameters, see paramSegmentTypes.
+func newHandlerPlan(h baseHandler, names, types []string, lookup converterLookup, registered registeredLookup) *handlerPlan {
+	fn := reflect.ValueOf(h)
+	t := fn.Type()
+	plan := &handlerPlan{fn: fn}
+	switch f := h.(type) {
+	case func(http.ResponseWriter, *http.Request):
+		plan.direct = f
+	case http.HandlerFunc:
+		plan.direct = f
+	case HandlerFunc:
+		plan.typed = func(w http.ResponseWriter, req *http.Request, ps PathParams) error {
+			f(w, req, ps)
+			return nil
+		}
+	}
+	plan.out, _ = handlerResults(t)
+
+	for i := 0; i < t.NumIn(); i++ {
+		kind, ok := requestArg(t.In(i), lookup)
+		switch {
+		case ok && kind == argPathStruct:
+			bindPath, err := newPathBinder(t.In(i), names, types, lookup, registered)
+			if err != nil {
+				bindPath = func(ps PathParams) (reflect.Value, error) { return reflect.Value{}, err }
+			}
+			plan.in = append(plan.in, argPlan{kind: kind, bindPath: bindPath})
+			plan.bindsPath = true
+			continue
+		case ok && kind == argPathParams:
+			plan.in = append(plan.in, argPlan{kind: kind})
+			plan.bindsPath = true
+			continue
+		case ok:
+			if kind == argBody {
+				plan.hasBody = true
+			}
+			bind, err := newRequestBinder(kind, t.In(i), lookup)
+			if err != nil {
+				bind = func(req *http.Request) (reflect.Value, error) { return reflect.Value{}, err }
+			}
+			plan.in = append(plan.in, argPlan{kind: kind, bind: bind})
+			continue
+		}
+
+		name, typ := fmt.Sprintf("#%d", plan.numParams), ""
+		if plan.numParams < len(names) {
+			name = names[plan.numParams]
+		}
+		if plan.numParams < len(types) {
+			typ = types[plan.numParams]
+		}
+		plan.in = append(plan.in, argPlan{kind: argParam, decode: newParamDecoder(name, t.In(i), segmentLookup(typ, lookup))})
+		plan.numParams++
+	}
+	numIn := t.NumIn()
+	plan.argsPool.New = func() interface{} {
+		args := make([]reflect.Value, numIn)
+		return &args
+	}
+	return plan
+}
+
+func newParamDecoder(name string, t reflect.Type, lookup converterLookup) paramDecoder {
+	conv, ok := lookup(t)
+	if !ok {
+		return func(raw string) (reflect.Value, error) {
+			return reflect.Value{}, &ParamError{Name: name, Raw: raw, Err: errors.Errorf("unsupported param type %v", t)}
+		}
+	}
+	return func(raw string) (reflect.Value, error) {
+		v, err := conv(raw)
+		if err != nil {
+			return reflect.Value{}, &ParamError{Name: name, Raw: raw, Err: err}
+		}
+		return v, nil
+	}
+}
+
+// args returns the handler args built from the request and the path parameters.
+// returned args are pooled, should be released via release after called.
+func (p *handlerPlan) args(w http.ResponseWriter, req *http.Request, ps PathParams) (*[]reflect.Value, error) {
+	if !p.bindsPath && p.numParams != len(ps) {
+		return nil, errors.Wrapf(ErrNotFoundHandler, "path=%s, handler=%v", req.URL.Path, p.fn.Type())
+	}
+
+	buf := p.argsPool.Get().(*[]reflect.Value)
+	args := *buf
+	n := 0
+	for i, a := range p.in {
+		switch a.kind {
+		case argResponseWriter:
+			args[i] = reflect.ValueOf(w)
+		case argRequest:
+			args[i] = reflect.ValueOf(req)
+		case argContext:
+			args[i] = reflect.ValueOf(req.Context())
+		case argHeader:
+			args[i] = reflect.ValueOf(req.Header)
+		case argQuery:
+			args[i] = reflect.ValueOf(req.URL.Query())
+		case argPathParams:
+			args[i] = reflect.ValueOf(ps)
+		case argPathStruct:
+			v, err := a.bindPath(ps)
+			if err != nil {
+				p.release(buf)
+				return nil, errors.Wrapf(err, "path=%s", req.URL.Path)
+			}
+			args[i] = v
+		case argQueryStruct, argBody:
+			v, err := a.bind(req)
+			if err != nil {
+				p.release(buf)
+				return nil, errors.Wrapf(err, "path=%s", req.URL.Path)
+			}
+			args[i] = v
+		case argParam:
+			v, err := a.decode(ps[n].Value)
+			n++
+			if err != nil {
+				p.release(buf)
+				return nil, errors.Wrapf(err, "path=%s", req.URL.Path)
+			}
+			args[i] = v
+		}
+	}
+	return buf, nil
+}
+
+func (p *handlerPlan) release(buf *[]reflect.Value) {
+	args := *buf
+	for i := range args {
+		args[i] = reflect.Value{}
+	}
+	p.argsPool.Put(buf)
+}
+
+// result returns the error or the value returned from the handler.
+// value is invalid when the handler does not return value.
+func (p *handlerPlan) result(out []reflect.Value) (reflect.Value, error) {
+	switch p.out {
+	case resultError:
+		err, _ := out[0].Interface().(error)
+		return reflect.Value{}, err
+	case resultValueError:
+		if err, _ := out[1].Interface().(error); err != nil {
+			return reflect.Value{}, err
+		}
+		return out[0], nil
+	}
+	return reflect.Value{}, nil
+}
@@ -0,0 +1,165 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestValidateHandler(t *testing.T) {
+	type invalidValidationParam struct{}
+	type itemID int64
+
+	cases := []struct {
+		path    string
+		handler baseHandler
+		expect  error
+	}{
+		{"/", dummyHandler, nil},
+		{"/:id/:name", dummyHandlerWithParams, nil},
+		{"/:id", dummyHandlerWithValidationParams, nil},
+		{"/static/*filepath", func(w http.ResponseWriter, req *http.Request, path string) {}, nil},
+		{"/", "not func", ErrInvalidHandler},
+		{"/", nil, ErrInvalidHandler},
+		// handlers are not required to start with (http.ResponseWriter, *http.Request)
+		{"/", func(req *http.Request, w http.ResponseWriter) {}, nil},
+		{"/", func(w http.ResponseWriter) {}, nil},
+		{"/", func() {}, nil},
+		{"/:id", func(req *http.Request, id int) (interface{}, error) { return nil, nil }, nil},
+		{"/:id", func(id int) error { return nil }, nil},
+		{"/:id", func(id int, ctx context.Context, h http.Header, q url.Values, req *http.Request, w http.ResponseWriter) {
+		}, nil},
+		{"/:id/:name", func(id int, ctx context.Context, name string) {}, nil},
+		{"/", func(body *dummyBody) {}, nil},
+		{"/", func(body struct{ Name string }) {}, ErrInvalidHandler},
+		{"/", func(w http.ResponseWriter, w2 http.ResponseWriter) {}, ErrInvalidHandler},
+		{"/", func(ctx context.Context, ctx2 context.Context) {}, ErrInvalidHandler},
+		{"/", func(req *http.Request) interface{} { return nil }, ErrInvalidHandler},
+		{"/", func(req *http.Request) (interface{}, interface{}) { return nil, nil }, ErrInvalidHandler},
+		{"/", func(req *http.Request) (int, int, error) { return 0, 0, nil }, ErrInvalidHandler},
+		{"/", func(w http.ResponseWriter, req *http.Request, args ...string) {}, ErrInvalidHandler},
+		{"/:id", dummyHandler, ErrInvalidHandler},
+		{"/:id", dummyHandlerWithParams, ErrInvalidHandler},
+		{"/:id", func(w http.ResponseWriter, req *http.Request, v invalidValidationParam) {}, ErrInvalidHandler},
+		{"/:id", func(w http.ResponseWriter, req *http.Request, v *invalidValidationParam) {}, ErrInvalidHandler},
+		{"id", dummyHandler, ErrInvalidPathFormat},
+		{"/:org/:repo", func(p *repoPath) {}, nil},
+		{"/:org/:repo/:id", func(w http.ResponseWriter, p repoPath) {}, nil},
+		{"/:org", func(p *repoPath) {}, ErrInvalidHandler},
+		{"/:org/:repo", func(p *repoPath, id int) {}, ErrInvalidHandler},
+		{"/:org/:repo", func(p *repoPath, p2 *repoPath) {}, ErrInvalidHandler},
+		{"/:org", func(p struct {
+			Org   int    `path:"org"`
+			State string `query:"state"`
+		}) {
+		}, ErrInvalidHandler},
+		{"/:org", func(p struct {
+			Org  int    `path:"org"`
+			Name string `form:"name"`
+		}) {
+		}, ErrInvalidHandler},
+		{"/:id:int/:slug:alpha", func(id int, slug string) {}, nil},
+		{"/:id:int", func(id float64) {}, nil},
+		{"/:id:int", func(id string) {}, nil},
+		{"/:id:int", func(id bool) {}, ErrInvalidHandler},
+		{"/:slug:alpha", func(slug int) {}, ErrInvalidHandler},
+		{"/:uuid:uuid", func(uuid uint) {}, ErrInvalidHandler},
+		{"/:id:int", func(w http.ResponseWriter, req *http.Request, v *dummyValidationParam) {}, ErrInvalidHandler},
+		{"/:id:int", func(id int64) {}, nil},
+		{"/:id:int", func(id itemID) {}, nil},
+		{"/:id:int", func(id uint) {}, ErrInvalidHandler},
+		{"/:id:int", func(id int32) {}, ErrInvalidHandler},
+		{"/:id:int", func(id time.Duration) {}, ErrInvalidHandler},
+		{"/{id:int}", func(id int) {}, nil},
+		{"/{id:int}", func(id bool) {}, ErrInvalidHandler},
+		{"/:id:int", func(p struct {
+			ID int `path:"id"`
+		}) {
+		}, nil},
+		{"/:id:int", func(p struct {
+			ID uint `path:"id"`
+		}) {
+		}, ErrInvalidHandler},
+		{"/:id:float", func(id float64) {}, ErrInvalidPathFormat},
+		{"/:org", func(p *struct {
+			Org []string `path:"org"`
+		}) {
+		}, ErrInvalidHandler},
+	}
+	for i, c := range cases {
+		err := validateHandler(c.path, c.handler, builtinConverter, nil)
+		if errors.Cause(err) != c.expect {
+			t.Errorf("#%d: want error:%v, got error:%v", i, c.expect, err)
+		}
+	}
+}
+
+func TestParamNames(t *testing.T) {
+	cases := []struct {
+		input  string
+		expect []string
+	}{
+		{"/", []string{}},
+		{"/user/:id/", []string{"id"}},
+		{"/user/:id/follow/:target/*filepath", []string{"id", "target", "filepath"}},
+		{"/user/:id<[0-9]+>/{name:[a-z]+\\.txt}/{tab}", []string{"id", "name", "tab"}},
+		{"/user/:id:int/:slug:alpha/*filepath", []string{"id", "slug", "filepath"}},
+	}
+	for i, c := range cases {
+		result, err := paramNames(c.input)
+		if err != nil {
+			t.Fatalf("#%d: want no error, got %v", i, err)
+		}
+		if len(result) != len(c.expect) {
+			t.Fatalf("#%d: want:%v, got:%v", i, c.expect, result)
+		}
+		for j := range result {
+			if result[j] != c.expect[j] {
+				t.Errorf("#%d: want:%v, got:%v", i, c.expect, result)
+			}
+		}
+	}
+}
+
+func TestNewHandlerPlan(t *testing.T) {
+	cases := []struct {
+		handler      baseHandler
+		names        []string
+		expectDirect bool
+		expectNumDec int
+	}{
+		{dummyHandler, nil, true, 0},
+		{http.HandlerFunc(dummyHandler), nil, true, 0},
+		{dummyHandlerWithParams, []string{"id", "name"}, false, 2},
+		{dummyHandlerWithValidationParams, []string{"id"}, false, 1},
+	}
+	for i, c := range cases {
+		plan := newHandlerPlan(c.handler, c.names, nil, builtinConverter, nil)
+		if (plan.direct != nil) != c.expectDirect {
+			t.Errorf("#%d: want direct:%t, got direct:%t", i, c.expectDirect, plan.direct != nil)
+		}
+		if plan.numParams != c.expectNumDec {
+			t.Errorf("#%d: want params:%d, got params:%d", i, c.expectNumDec, plan.numParams)
+		}
+	}
+}
+
+func TestHandlerPlanArgsError(t *testing.T) {
+	plan := newHandlerPlan(func(w http.ResponseWriter, req *http.Request, id int, ok bool) {}, []string{"id", "ok"}, nil, builtinConverter, nil)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+
+	_, err := plan.args(w, req, PathParams{{Value: "10"}, {Value: "notbool"}})
+	if errors.Cause(err) != ErrInvalidParam {
+		t.Fatalf("want error:%v, got error:%v", ErrInvalidParam, err)
+	}
+	if !strings.Contains(err.Error(), "param=ok") {
+		t.Errorf("want error contains param name, got %v", err)
+	}
+}
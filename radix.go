@@ -0,0 +1,334 @@
+package router
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RadixTree is implemented Routing via prefix-compressed radix tree.
+// static children are indexed by the first byte, and the path parameters are captured while walking the tree.
+// the matching rules are same as Trie.
+type RadixTree struct {
+	root map[string]*radixNode
+}
+
+// radixNode is the node of RadixTree.
+// static children are stored in children, and indices holds the first byte of each child prefix.
+type radixNode struct {
+	prefix   string
+	indices  string
+	children []*radixNode
+	// ":param" child, matches a non-empty segment
+	param *radixNode
+	// ":param<regexp>" and ":param:type" children, tried before param in order of registration
+	constrained []*radixNode
+	// key and constraint of the constrained param node
+	key        string
+	constraint func(string) bool
+	// "*wildcard" child, matches the rest of path
+	wild *radixNode
+
+	// origin URL path, empty when not registered
+	path    string
+	handler baseHandler
+	// precompiled on registration via Router, nil otherwise
+	plan *handlerPlan
+}
+
+// NewRadixTree return initialized RadixTree struct
+func NewRadixTree() *RadixTree {
+	return &RadixTree{root: make(map[string]*radixNode, 7)}
+}
+
+// Lookup returns a HandlerData matching path and method without allocation.
+// returns ErrInvalidPathFormat or ErrPathNotFound as is, and the path parameters are extracted from the looked up path on demand.
+func (t *RadixTree) Lookup(path string, method string) (HandlerData, error) {
+	n, err := t.match(path, method, nil)
+	if err != nil {
+		return HandlerData{}, err
+	}
+	return HandlerData{
+		handler: n.handler,
+		plan:    n.plan,
+		pattern: n.path,
+		path:    trimTrailingSlash(trimQueryString(path)),
+	}, nil
+}
+
+// LookupParams is like Lookup but stores the path parameters to ps without allocation.
+// ps is reset before stored, and reused its capacity. params of the returned HandlerData is nil.
+func (t *RadixTree) LookupParams(method, path string, ps *PathParams) (HandlerData, bool) {
+	*ps = (*ps)[:0]
+	n, err := t.match(path, method, ps)
+	if err != nil {
+		return HandlerData{}, false
+	}
+	fillParamKeys(n.path, *ps)
+	return HandlerData{handler: n.handler, pattern: n.path, plan: n.plan}, true
+}
+
+// AllowedMethods returns the sorted HTTP methods that have a route matching path
+func (t *RadixTree) AllowedMethods(path string) []string {
+	methods := []string{}
+	for method := range t.root {
+		if _, err := t.match(path, method, nil); err == nil {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func (t *RadixTree) match(path string, method string, ps *PathParams) (*radixNode, error) {
+	path = trimQueryString(path)
+	if len(path) == 0 || path[0] != '/' {
+		return nil, ErrInvalidPathFormat
+	}
+	root, ok := t.root[method]
+	if !ok {
+		return nil, ErrPathNotFound
+	}
+	// "//" is the empty segment under the root like Trie, not the root itself
+	if path == "//" {
+		if n := root.matchRootWild(ps); n != nil {
+			return n, nil
+		}
+		return nil, ErrPathNotFound
+	}
+	if n := root.match(trimTrailingSlash(path), ps); n != nil {
+		return n, nil
+	}
+	return nil, ErrPathNotFound
+}
+
+// matchRootWild returns the wildcard registered under the root, e.g. "/*filepath", matched the empty rest of path
+func (n *radixNode) matchRootWild(ps *PathParams) *radixNode {
+	i := strings.IndexByte(n.indices, '/')
+	if i < 0 {
+		return nil
+	}
+	if child := n.children[i]; child.prefix == "/" && child.wild != nil && len(child.wild.path) != 0 {
+		appendParam(ps, "")
+		return child.wild
+	}
+	return nil
+}
+
+// Insert registered new node
+func (t *RadixTree) Insert(method, path string, handler baseHandler) error {
+	if len(path) == 0 || path[0] != '/' {
+		return errors.Wrapf(ErrInvalidPathFormat, "failed insert. path=%s, method=%s", path, method)
+	}
+	root, ok := t.root[method]
+	if !ok {
+		root = &radixNode{}
+		t.root[method] = root
+	}
+
+	n := root
+	rest := trimTrailingSlash(path)
+	for len(rest) > 0 {
+		switch {
+		case isParamSegment(rest):
+			end := strings.Index(rest, "/")
+			if end < 0 {
+				end = len(rest)
+			}
+			seg, err := parseParamSegment(rest[:end])
+			if err != nil {
+				return errors.Wrapf(err, "failed insert. path=%s, method=%s", path, method)
+			}
+			n = n.insertParam(seg)
+			rest = rest[end:]
+		case isWildcardSegment(rest):
+			if strings.Contains(rest, "/") {
+				return errors.Wrapf(ErrAlreadyWildcardPathRegistered, "failed insert. path=%s, method=%s", path, method)
+			}
+			if rest == TokenWildcard {
+				return errors.Wrapf(ErrInvalidPathFormat, "empty wildcard name. path=%s, method=%s", path, method)
+			}
+			if n.wild == nil {
+				n.wild = &radixNode{}
+			}
+			n = n.wild
+			rest = ""
+		default:
+			end := nextParamSegment(rest)
+			n = n.insertStatic(rest[:end])
+			rest = rest[end:]
+		}
+	}
+
+	if len(n.path) != 0 {
+		return errors.Wrapf(ErrAlreadyPathRegistered, "method=%s, path=%s", method, path)
+	}
+	n.path = path
+	n.handler = handler
+	n.plan = routePlan(handler)
+	return nil
+}
+
+// insertParam returns the param child of n, the constrained children are shared by the same constraint
+func (n *radixNode) insertParam(seg paramSegment) *radixNode {
+	if seg.match == nil {
+		if n.param == nil {
+			n.param = &radixNode{}
+		}
+		return n.param
+	}
+	key := seg.key()
+	for _, child := range n.constrained {
+		if child.key == key {
+			return child
+		}
+	}
+	child := &radixNode{key: key, constraint: seg.match}
+	n.constrained = append(n.constrained, child)
+	return child
+}
+
+// insertStatic returns the descendant node ended with s, the nodes are split when s ends in the middle of the prefix
+func (n *radixNode) insertStatic(s string) *radixNode {
+	for len(s) > 0 {
+		i := strings.IndexByte(n.indices, s[0])
+		if i < 0 {
+			child := &radixNode{prefix: s}
+			n.indices += s[:1]
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := commonPrefixLen(child.prefix, s)
+		if l < len(child.prefix) {
+			// split the child into the common prefix and the rest
+			split := *child
+			split.prefix = child.prefix[l:]
+			*child = radixNode{
+				prefix:   child.prefix[:l],
+				indices:  split.prefix[:1],
+				children: []*radixNode{&split},
+			}
+		}
+		n = child
+		s = s[l:]
+	}
+	return n
+}
+
+// match returns the registered node matched path, path is the rest after the prefix of n.
+// children are tried in order of static, param and wildcard, and backtracks when the descendants do not match.
+func (n *radixNode) match(path string, ps *PathParams) *radixNode {
+	if len(path) == 0 {
+		if len(n.path) != 0 {
+			return n
+		}
+		// wildcard matches the empty rest of path, e.g. "/files" matches "/files/*filepath"
+		if n.wild != nil && len(n.wild.path) != 0 {
+			appendParam(ps, "")
+			return n.wild
+		}
+		if i := strings.IndexByte(n.indices, '/'); i >= 0 {
+			if child := n.children[i]; child.prefix == "/" && len(child.path) == 0 && child.wild != nil && len(child.wild.path) != 0 {
+				appendParam(ps, "")
+				return child.wild
+			}
+		}
+		return nil
+	}
+
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.prefix) {
+			if m := child.match(path[len(child.prefix):], ps); m != nil {
+				return m
+			}
+		} else if len(child.prefix) == len(path)+1 && strings.HasSuffix(child.prefix, "/") && strings.HasPrefix(child.prefix, path) {
+			// the rest of path is ended before the last "/" of the prefix, e.g. "/files" and "/files/*filepath"
+			if len(child.path) == 0 && child.wild != nil && len(child.wild.path) != 0 {
+				appendParam(ps, "")
+				return child.wild
+			}
+		}
+	}
+
+	if n.param != nil || len(n.constrained) != 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			seg := path[:end]
+			for _, child := range n.constrained {
+				if !child.constraint(seg) {
+					continue
+				}
+				if m := child.matchParam(seg, path[end:], ps); m != nil {
+					return m
+				}
+			}
+			if n.param != nil {
+				if m := n.param.matchParam(seg, path[end:], ps); m != nil {
+					return m
+				}
+			}
+		}
+	}
+
+	// wildcard does not begin with the empty segment, e.g. "/users//profile" does not match "/users/*path"
+	if n.wild != nil && len(n.wild.path) != 0 && path[0] != '/' {
+		appendParam(ps, path)
+		return n.wild
+	}
+	return nil
+}
+
+// matchParam captures seg as the param of n, and matches the rest of path.
+// the captured param is dropped when the rest does not match.
+func (n *radixNode) matchParam(seg, rest string, ps *PathParams) *radixNode {
+	size := paramsLen(ps)
+	appendParam(ps, seg)
+	if m := n.match(rest, ps); m != nil {
+		return m
+	}
+	truncateParams(ps, size)
+	return nil
+}
+
+// isParamSegment reports whether s begins with ":param" or "{param}" segment
+func isParamSegment(s string) bool {
+	return len(s) > 0 && isParamKey(s)
+}
+
+// isWildcardSegment reports whether s begins with "*wildcard" segment
+func isWildcardSegment(s string) bool {
+	return len(s) > 0 && string(s[0]) == TokenWildcard
+}
+
+// nextParamSegment returns the index of the next ":param" or "*wildcard" segment, or len(s) when not found
+func nextParamSegment(s string) int {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] == '/' && (isParamSegment(s[i+1:]) || isWildcardSegment(s[i+1:])) {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// trimTrailingSlash trims the last "/" except the root path
+func trimTrailingSlash(path string) string {
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		return path[:len(path)-1]
+	}
+	return path
+}
@@ -0,0 +1,544 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// githubAPI is the routes of the GitHub API v3, used for comparing Routing implementations
+var githubAPI = []struct {
+	method string
+	path   string
+}{
+	// OAuth Authorizations
+	{"GET", "/authorizations"},
+	{"GET", "/authorizations/:id"},
+	{"POST", "/authorizations"},
+	{"PUT", "/authorizations/clients/:client_id"},
+	{"PATCH", "/authorizations/:id"},
+	{"DELETE", "/authorizations/:id"},
+	{"GET", "/applications/:client_id/tokens/:access_token"},
+	{"DELETE", "/applications/:client_id/tokens"},
+	{"DELETE", "/applications/:client_id/tokens/:access_token"},
+
+	// Activity
+	{"GET", "/events"},
+	{"GET", "/repos/:owner/:repo/events"},
+	{"GET", "/networks/:owner/:repo/events"},
+	{"GET", "/orgs/:org/events"},
+	{"GET", "/users/:user/received_events"},
+	{"GET", "/users/:user/received_events/public"},
+	{"GET", "/users/:user/events"},
+	{"GET", "/users/:user/events/public"},
+	{"GET", "/users/:user/events/orgs/:org"},
+	{"GET", "/feeds"},
+	{"GET", "/notifications"},
+	{"GET", "/repos/:owner/:repo/notifications"},
+	{"PUT", "/notifications"},
+	{"PUT", "/repos/:owner/:repo/notifications"},
+	{"GET", "/notifications/threads/:id"},
+	{"PATCH", "/notifications/threads/:id"},
+	{"GET", "/notifications/threads/:id/subscription"},
+	{"PUT", "/notifications/threads/:id/subscription"},
+	{"DELETE", "/notifications/threads/:id/subscription"},
+	{"GET", "/repos/:owner/:repo/stargazers"},
+	{"GET", "/users/:user/starred"},
+	{"GET", "/user/starred"},
+	{"GET", "/user/starred/:owner/:repo"},
+	{"PUT", "/user/starred/:owner/:repo"},
+	{"DELETE", "/user/starred/:owner/:repo"},
+	{"GET", "/repos/:owner/:repo/subscribers"},
+	{"GET", "/users/:user/subscriptions"},
+	{"GET", "/user/subscriptions"},
+	{"GET", "/repos/:owner/:repo/subscription"},
+	{"PUT", "/repos/:owner/:repo/subscription"},
+	{"DELETE", "/repos/:owner/:repo/subscription"},
+	{"GET", "/user/subscriptions/:owner/:repo"},
+	{"PUT", "/user/subscriptions/:owner/:repo"},
+	{"DELETE", "/user/subscriptions/:owner/:repo"},
+
+	// Gists
+	{"GET", "/users/:user/gists"},
+	{"GET", "/gists"},
+	{"GET", "/gists/public"},
+	{"GET", "/gists/starred"},
+	{"GET", "/gists/:id"},
+	{"POST", "/gists"},
+	{"PATCH", "/gists/:id"},
+	{"PUT", "/gists/:id/star"},
+	{"DELETE", "/gists/:id/star"},
+	{"GET", "/gists/:id/star"},
+	{"POST", "/gists/:id/forks"},
+	{"DELETE", "/gists/:id"},
+
+	// Git Data
+	{"GET", "/repos/:owner/:repo/git/blobs/:sha"},
+	{"POST", "/repos/:owner/:repo/git/blobs"},
+	{"GET", "/repos/:owner/:repo/git/commits/:sha"},
+	{"POST", "/repos/:owner/:repo/git/commits"},
+	{"GET", "/repos/:owner/:repo/git/refs/*ref"},
+	{"POST", "/repos/:owner/:repo/git/refs"},
+	{"PATCH", "/repos/:owner/:repo/git/refs/*ref"},
+	{"DELETE", "/repos/:owner/:repo/git/refs/*ref"},
+	{"GET", "/repos/:owner/:repo/git/tags/:sha"},
+	{"POST", "/repos/:owner/:repo/git/tags"},
+	{"GET", "/repos/:owner/:repo/git/trees/:sha"},
+	{"POST", "/repos/:owner/:repo/git/trees"},
+
+	// Issues
+	{"GET", "/issues"},
+	{"GET", "/user/issues"},
+	{"GET", "/orgs/:org/issues"},
+	{"GET", "/repos/:owner/:repo/issues"},
+	{"GET", "/repos/:owner/:repo/issues/:number"},
+	{"POST", "/repos/:owner/:repo/issues"},
+	{"PATCH", "/repos/:owner/:repo/issues/:number"},
+	{"GET", "/repos/:owner/:repo/assignees"},
+	{"GET", "/repos/:owner/:repo/assignees/:assignee"},
+	{"GET", "/repos/:owner/:repo/issues/:number/comments"},
+	{"GET", "/repos/:owner/:repo/issues/comments"},
+	{"GET", "/repos/:owner/:repo/issues/comments/:id"},
+	{"POST", "/repos/:owner/:repo/issues/:number/comments"},
+	{"PATCH", "/repos/:owner/:repo/issues/comments/:id"},
+	{"DELETE", "/repos/:owner/:repo/issues/comments/:id"},
+	{"GET", "/repos/:owner/:repo/issues/:number/events"},
+	{"GET", "/repos/:owner/:repo/issues/events"},
+	{"GET", "/repos/:owner/:repo/issues/events/:id"},
+	{"GET", "/repos/:owner/:repo/labels"},
+	{"GET", "/repos/:owner/:repo/labels/:name"},
+	{"POST", "/repos/:owner/:repo/labels"},
+	{"PATCH", "/repos/:owner/:repo/labels/:name"},
+	{"DELETE", "/repos/:owner/:repo/labels/:name"},
+	{"GET", "/repos/:owner/:repo/issues/:number/labels"},
+	{"POST", "/repos/:owner/:repo/issues/:number/labels"},
+	{"DELETE", "/repos/:owner/:repo/issues/:number/labels/:name"},
+	{"PUT", "/repos/:owner/:repo/issues/:number/labels"},
+	{"DELETE", "/repos/:owner/:repo/issues/:number/labels"},
+	{"GET", "/repos/:owner/:repo/milestones/:number/labels"},
+	{"GET", "/repos/:owner/:repo/milestones"},
+	{"GET", "/repos/:owner/:repo/milestones/:number"},
+	{"POST", "/repos/:owner/:repo/milestones"},
+	{"PATCH", "/repos/:owner/:repo/milestones/:number"},
+	{"DELETE", "/repos/:owner/:repo/milestones/:number"},
+
+	// Miscellaneous
+	{"GET", "/emojis"},
+	{"GET", "/gitignore/templates"},
+	{"GET", "/gitignore/templates/:name"},
+	{"POST", "/markdown"},
+	{"POST", "/markdown/raw"},
+	{"GET", "/meta"},
+	{"GET", "/rate_limit"},
+
+	// Organizations
+	{"GET", "/users/:user/orgs"},
+	{"GET", "/user/orgs"},
+	{"GET", "/orgs/:org"},
+	{"PATCH", "/orgs/:org"},
+	{"GET", "/orgs/:org/members"},
+	{"GET", "/orgs/:org/members/:user"},
+	{"DELETE", "/orgs/:org/members/:user"},
+	{"GET", "/orgs/:org/public_members"},
+	{"GET", "/orgs/:org/public_members/:user"},
+	{"PUT", "/orgs/:org/public_members/:user"},
+	{"DELETE", "/orgs/:org/public_members/:user"},
+	{"GET", "/orgs/:org/teams"},
+	{"GET", "/teams/:id"},
+	{"POST", "/orgs/:org/teams"},
+	{"PATCH", "/teams/:id"},
+	{"DELETE", "/teams/:id"},
+	{"GET", "/teams/:id/members"},
+	{"GET", "/teams/:id/members/:user"},
+	{"PUT", "/teams/:id/members/:user"},
+	{"DELETE", "/teams/:id/members/:user"},
+	{"GET", "/teams/:id/repos"},
+	{"GET", "/teams/:id/repos/:owner/:repo"},
+	{"PUT", "/teams/:id/repos/:owner/:repo"},
+	{"DELETE", "/teams/:id/repos/:owner/:repo"},
+	{"GET", "/user/teams"},
+
+	// Pull Requests
+	{"GET", "/repos/:owner/:repo/pulls"},
+	{"GET", "/repos/:owner/:repo/pulls/:number"},
+	{"POST", "/repos/:owner/:repo/pulls"},
+	{"PATCH", "/repos/:owner/:repo/pulls/:number"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/commits"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/files"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/merge"},
+	{"PUT", "/repos/:owner/:repo/pulls/:number/merge"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/comments"},
+	{"GET", "/repos/:owner/:repo/pulls/comments"},
+	{"GET", "/repos/:owner/:repo/pulls/comments/:number"},
+	{"PUT", "/repos/:owner/:repo/pulls/:number/comments"},
+	{"PATCH", "/repos/:owner/:repo/pulls/comments/:number"},
+	{"DELETE", "/repos/:owner/:repo/pulls/comments/:number"},
+
+	// Repositories
+	{"GET", "/user/repos"},
+	{"GET", "/users/:user/repos"},
+	{"GET", "/orgs/:org/repos"},
+	{"GET", "/repositories"},
+	{"POST", "/user/repos"},
+	{"POST", "/orgs/:org/repos"},
+	{"GET", "/repos/:owner/:repo"},
+	{"PATCH", "/repos/:owner/:repo"},
+	{"GET", "/repos/:owner/:repo/contributors"},
+	{"GET", "/repos/:owner/:repo/languages"},
+	{"GET", "/repos/:owner/:repo/teams"},
+	{"GET", "/repos/:owner/:repo/tags"},
+	{"GET", "/repos/:owner/:repo/branches"},
+	{"GET", "/repos/:owner/:repo/branches/:branch"},
+	{"DELETE", "/repos/:owner/:repo"},
+	{"GET", "/repos/:owner/:repo/collaborators"},
+	{"GET", "/repos/:owner/:repo/collaborators/:user"},
+	{"PUT", "/repos/:owner/:repo/collaborators/:user"},
+	{"DELETE", "/repos/:owner/:repo/collaborators/:user"},
+	{"GET", "/repos/:owner/:repo/comments"},
+	{"GET", "/repos/:owner/:repo/commits/:sha/comments"},
+	{"POST", "/repos/:owner/:repo/commits/:sha/comments"},
+	{"GET", "/repos/:owner/:repo/comments/:id"},
+	{"PATCH", "/repos/:owner/:repo/comments/:id"},
+	{"DELETE", "/repos/:owner/:repo/comments/:id"},
+	{"GET", "/repos/:owner/:repo/commits"},
+	{"GET", "/repos/:owner/:repo/commits/:sha"},
+	{"GET", "/repos/:owner/:repo/readme"},
+	{"GET", "/repos/:owner/:repo/contents/*path"},
+	{"PUT", "/repos/:owner/:repo/contents/*path"},
+	{"DELETE", "/repos/:owner/:repo/contents/*path"},
+	{"GET", "/repos/:owner/:repo/:archive_format/:ref"},
+	{"GET", "/repos/:owner/:repo/keys"},
+	{"GET", "/repos/:owner/:repo/keys/:id"},
+	{"POST", "/repos/:owner/:repo/keys"},
+	{"PATCH", "/repos/:owner/:repo/keys/:id"},
+	{"DELETE", "/repos/:owner/:repo/keys/:id"},
+	{"GET", "/repos/:owner/:repo/downloads"},
+	{"GET", "/repos/:owner/:repo/downloads/:id"},
+	{"DELETE", "/repos/:owner/:repo/downloads/:id"},
+	{"GET", "/repos/:owner/:repo/forks"},
+	{"POST", "/repos/:owner/:repo/forks"},
+	{"GET", "/repos/:owner/:repo/hooks"},
+	{"GET", "/repos/:owner/:repo/hooks/:id"},
+	{"POST", "/repos/:owner/:repo/hooks"},
+	{"PATCH", "/repos/:owner/:repo/hooks/:id"},
+	{"POST", "/repos/:owner/:repo/hooks/:id/tests"},
+	{"DELETE", "/repos/:owner/:repo/hooks/:id"},
+	{"POST", "/repos/:owner/:repo/merges"},
+	{"GET", "/repos/:owner/:repo/releases"},
+	{"GET", "/repos/:owner/:repo/releases/:id"},
+	{"POST", "/repos/:owner/:repo/releases"},
+	{"PATCH", "/repos/:owner/:repo/releases/:id"},
+	{"DELETE", "/repos/:owner/:repo/releases/:id"},
+	{"GET", "/repos/:owner/:repo/releases/:id/assets"},
+	{"GET", "/repos/:owner/:repo/stats/contributors"},
+	{"GET", "/repos/:owner/:repo/stats/commit_activity"},
+	{"GET", "/repos/:owner/:repo/stats/code_frequency"},
+	{"GET", "/repos/:owner/:repo/stats/participation"},
+	{"GET", "/repos/:owner/:repo/stats/punch_card"},
+	{"GET", "/repos/:owner/:repo/statuses/:ref"},
+	{"POST", "/repos/:owner/:repo/statuses/:ref"},
+
+	// Search
+	{"GET", "/search/repositories"},
+	{"GET", "/search/code"},
+	{"GET", "/search/issues"},
+	{"GET", "/search/users"},
+	{"GET", "/legacy/issues/search/:owner/:repository/:state/:keyword"},
+	{"GET", "/legacy/repos/search/:keyword"},
+	{"GET", "/legacy/user/search/:keyword"},
+	{"GET", "/legacy/user/email/:email"},
+
+	// Users
+	{"GET", "/users/:user"},
+	{"GET", "/user"},
+	{"PATCH", "/user"},
+	{"GET", "/users"},
+	{"GET", "/user/emails"},
+	{"POST", "/user/emails"},
+	{"DELETE", "/user/emails"},
+	{"GET", "/users/:user/followers"},
+	{"GET", "/user/followers"},
+	{"GET", "/users/:user/following"},
+	{"GET", "/user/following"},
+	{"GET", "/user/following/:user"},
+	{"GET", "/users/:user/following/:target_user"},
+	{"PUT", "/user/following/:user"},
+	{"DELETE", "/user/following/:user"},
+	{"GET", "/users/:user/keys"},
+	{"GET", "/user/keys"},
+	{"GET", "/user/keys/:id"},
+	{"POST", "/user/keys"},
+	{"PATCH", "/user/keys/:id"},
+	{"DELETE", "/user/keys/:id"},
+}
+
+// githubRequestPath returns the request path replaced the params of the pattern with the names
+func githubRequestPath(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, p := range parts {
+		if len(p) != 0 && (isParamKey(p) || isWildcardKey(p)) {
+			parts[i] = p[1:]
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func newGitHubRouting(b testing.TB, routing Routing) Routing {
+	for _, r := range githubAPI {
+		if err := routing.Insert(r.method, r.path, r.path); err != nil {
+			b.Fatalf("want no error, got %v. method=%s, path=%s", err, r.method, r.path)
+		}
+	}
+	return routing
+}
+
+func TestRadixTreeGitHub(t *testing.T) {
+	trie := newGitHubRouting(t, NewTrie())
+	radix := newGitHubRouting(t, NewRadixTree())
+	for i, r := range githubAPI {
+		path := githubRequestPath(r.path)
+		want, err := trie.Lookup(path, r.method)
+		if err != nil {
+			t.Fatalf("#%d: want no error, got %v", i, err)
+		}
+		got, err := radix.Lookup(path, r.method)
+		if err != nil {
+			t.Errorf("#%d: want no error, got %v. path=%s", i, err, path)
+			continue
+		}
+		if got.pattern != want.pattern {
+			t.Errorf("#%d: want pattern:%s, got pattern:%s", i, want.pattern, got.pattern)
+		}
+		var wantPs, gotPs PathParams
+		want.appendParams(&wantPs)
+		got.appendParams(&gotPs)
+		if !reflect.DeepEqual(nilIfEmpty(gotPs), nilIfEmpty(wantPs)) {
+			t.Errorf("#%d: want params:%v, got params:%v", i, wantPs, gotPs)
+		}
+		if g, w := radix.(allowedMethodsRouting).AllowedMethods(path), trie.(allowedMethodsRouting).AllowedMethods(path); !reflect.DeepEqual(g, w) {
+			t.Errorf("#%d: want allowed methods:%v, got allowed methods:%v", i, w, g)
+		}
+	}
+}
+
+func TestRadixTreeSameAsTrie(t *testing.T) {
+	routes := [][]string{
+		{"/"},
+		{"/", "/users"},
+		{"/", "/*filepath"},
+		{"/*filepath"},
+		{"/:id", "/files/*filepath"},
+		{"/users", "/users/:id"},
+	}
+	inputs := []string{"/", "//", "///", "//?q=1", "/users", "/users/", "/users//", "/files", "/files/", "/files//", "/files//a", "/x//"}
+	for i, paths := range routes {
+		trie, radix := NewTrie(), NewRadixTree()
+		for _, p := range paths {
+			if err := trie.Insert("GET", p, p); err != nil {
+				t.Fatalf("#%d: want no error, got %v. path=%s", i, err, p)
+			}
+			if err := radix.Insert("GET", p, p); err != nil {
+				t.Fatalf("#%d: want no error, got %v. path=%s", i, err, p)
+			}
+		}
+		for _, input := range inputs {
+			want, wantErr := trie.Lookup(input, "GET")
+			got, gotErr := radix.Lookup(input, "GET")
+			if errors.Cause(gotErr) != errors.Cause(wantErr) {
+				t.Errorf("#%d %s: want error:%v, got error:%v", i, input, wantErr, gotErr)
+				continue
+			}
+			if got.pattern != want.pattern || !reflect.DeepEqual(nilIfEmpty(paramValues(got)), nilIfEmpty(paramValues(want))) {
+				t.Errorf("#%d %s: want pattern:%s params:%v, got pattern:%s params:%v", i, input, want.pattern, paramValues(want), got.pattern, paramValues(got))
+			}
+		}
+	}
+}
+
+func TestRadixTreeInsertSplit(t *testing.T) {
+	radix := NewRadixTree()
+	for _, p := range []string{"/users/news", "/users/new", "/users/:id", "/use", "/users/new/edit"} {
+		if err := radix.Insert("GET", p, p); err != nil {
+			t.Fatalf("want no error, got %v. path=%s", err, p)
+		}
+	}
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{"/use", "/use"},
+		{"/users/new", "/users/new"},
+		{"/users/news", "/users/news"},
+		{"/users/newx", "/users/:id"},
+		{"/users/ne", "/users/:id"},
+		{"/users/new/edit", "/users/new/edit"},
+	}
+	for i, c := range cases {
+		result, err := radix.Lookup(c.input, "GET")
+		if err != nil {
+			t.Errorf("#%d: want no error, got %v", i, err)
+			continue
+		}
+		if result.pattern != c.expect {
+			t.Errorf("#%d: want pattern:%s, got pattern:%s", i, c.expect, result.pattern)
+		}
+	}
+}
+
+func benchmarkRouting(b *testing.B, routing Routing, method, path string) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := routing.Lookup(path, method); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkRoutingAll(b *testing.B, routing Routing) {
+	paths := make([]string, len(githubAPI))
+	for i, r := range githubAPI {
+		paths[i] = githubRequestPath(r.path)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, r := range githubAPI {
+			if _, err := routing.Lookup(paths[j], r.method); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkTrieGitHubStatic(b *testing.B) {
+	benchmarkRouting(b, newGitHubRouting(b, NewTrie()), http.MethodGet, "/user/repos")
+}
+
+func BenchmarkRadixTreeGitHubStatic(b *testing.B) {
+	benchmarkRouting(b, newGitHubRouting(b, NewRadixTree()), http.MethodGet, "/user/repos")
+}
+
+func BenchmarkTrieGitHubParam(b *testing.B) {
+	benchmarkRouting(b, newGitHubRouting(b, NewTrie()), http.MethodGet, "/repos/julienschmidt/httprouter/stargazers")
+}
+
+func BenchmarkRadixTreeGitHubParam(b *testing.B) {
+	benchmarkRouting(b, newGitHubRouting(b, NewRadixTree()), http.MethodGet, "/repos/julienschmidt/httprouter/stargazers")
+}
+
+func BenchmarkTrieGitHubAll(b *testing.B) {
+	benchmarkRoutingAll(b, newGitHubRouting(b, NewTrie()))
+}
+
+func BenchmarkRadixTreeGitHubAll(b *testing.B) {
+	benchmarkRoutingAll(b, newGitHubRouting(b, NewRadixTree()))
+}
+
+func TestRadixTreeLookupAllocs(t *testing.T) {
+	radix := newGitHubRouting(t, NewRadixTree())
+	for i, r := range githubAPI {
+		path := githubRequestPath(r.path)
+		allocs := testing.AllocsPerRun(10, func() {
+			if _, err := radix.Lookup(path, r.method); err != nil {
+				t.Fatalf("#%d: want no error, got %v. path=%s", i, err, path)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("#%d: want allocs:0, got allocs:%v. path=%s", i, allocs, path)
+		}
+	}
+
+	misses := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/notfound/path"},
+		{http.MethodGet, "/repos/julienschmidt"},
+		{http.MethodConnect, "/user/repos"},
+		{http.MethodGet, "notfound"},
+	}
+	for i, m := range misses {
+		allocs := testing.AllocsPerRun(10, func() {
+			if _, err := radix.Lookup(m.path, m.method); err == nil {
+				t.Fatalf("#%d: want error, got nil. path=%s", i, m.path)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("#%d: want allocs:0 when not found, got allocs:%v. path=%s", i, allocs, m.path)
+		}
+	}
+}
+
+func TestLookupParamsAllocs(t *testing.T) {
+	routings := map[string]paramsRouting{
+		"Trie":      newGitHubRouting(t, NewTrie()).(paramsRouting),
+		"RadixTree": newGitHubRouting(t, NewRadixTree()).(paramsRouting),
+	}
+	for name, routing := range routings {
+		ps := make(PathParams, 0, paramsCap)
+		for i, r := range githubAPI {
+			path := githubRequestPath(r.path)
+			hd, ok := routing.LookupParams(r.method, path, &ps)
+			if !ok {
+				t.Errorf("%s #%d: want found, got not found. path=%s", name, i, path)
+				continue
+			}
+			names, _ := paramNames(r.path)
+			if hd.pattern != r.path || len(ps) != len(names) {
+				t.Errorf("%s #%d: want pattern:%s params:%d, got pattern:%s params:%d", name, i, r.path, len(names), hd.pattern, len(ps))
+				continue
+			}
+			for j, p := range ps {
+				if p.Key != names[j] || p.Value != names[j] {
+					t.Errorf("%s #%d: want param:%s=%s, got param:%s=%s", name, i, names[j], names[j], p.Key, p.Value)
+				}
+			}
+
+			allocs := testing.AllocsPerRun(10, func() {
+				routing.LookupParams(r.method, path, &ps)
+			})
+			if allocs != 0 {
+				t.Errorf("%s #%d: want allocs:0, got allocs:%v. path=%s", name, i, allocs, path)
+			}
+		}
+
+		allocs := testing.AllocsPerRun(10, func() {
+			routing.LookupParams(http.MethodGet, "/notfound/path", &ps)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: want allocs:0 when not found, got allocs:%v", name, allocs)
+		}
+	}
+}
+
+func benchmarkLookupParamsAll(b *testing.B, routing paramsRouting) {
+	paths := make([]string, len(githubAPI))
+	for i, r := range githubAPI {
+		paths[i] = githubRequestPath(r.path)
+	}
+	ps := make(PathParams, 0, paramsCap)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, r := range githubAPI {
+			if _, ok := routing.LookupParams(r.method, paths[j], &ps); !ok {
+				b.Fatalf("not found. path=%s", paths[j])
+			}
+		}
+	}
+}
+
+func BenchmarkTrieGitHubAllParams(b *testing.B) {
+	benchmarkLookupParamsAll(b, newGitHubRouting(b, NewTrie()).(paramsRouting))
+}
+
+func BenchmarkRadixTreeGitHubAllParams(b *testing.B) {
+	benchmarkLookupParamsAll(b, newGitHubRouting(b, NewRadixTree()).(paramsRouting))
+}
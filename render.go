@@ -0,0 +1,138 @@
+package router
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Renderer writes the value returned from the handler to the response
+type Renderer interface {
+	Render(w http.ResponseWriter, req *http.Request, v interface{}) error
+}
+
+// JSONRenderer renders the value as JSON
+type JSONRenderer struct{}
+
+// Render implements Renderer
+func (JSONRenderer) Render(w http.ResponseWriter, req *http.Request, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, err = w.Write(b)
+	return err
+}
+
+// XMLRenderer renders the value as XML
+type XMLRenderer struct{}
+
+// Render implements Renderer
+func (XMLRenderer) Render(w http.ResponseWriter, req *http.Request, v interface{}) error {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if _, err = w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// NegotiateRenderer chooses the Renderer by the Accept header of the request
+type NegotiateRenderer struct {
+	// used when the Accept header is empty or not matched
+	Default   Renderer
+	renderers []mediaRenderer
+}
+
+type mediaRenderer struct {
+	mediaType string
+	renderer  Renderer
+}
+
+// NewNegotiateRenderer returns NegotiateRenderer with the default Renderer
+func NewNegotiateRenderer(def Renderer) *NegotiateRenderer {
+	return &NegotiateRenderer{Default: def}
+}
+
+// Register register the Renderer for the media type. e.g. "application/json"
+func (n *NegotiateRenderer) Register(mediaType string, r Renderer) *NegotiateRenderer {
+	n.renderers = append(n.renderers, mediaRenderer{mediaType: mediaType, renderer: r})
+	return n
+}
+
+// Render implements Renderer
+func (n *NegotiateRenderer) Render(w http.ResponseWriter, req *http.Request, v interface{}) error {
+	return n.choose(req.Header.Get("Accept")).Render(w, req, v)
+}
+
+// choose returns the Renderer of the most preferred media type in the Accept header
+func (n *NegotiateRenderer) choose(accept string) Renderer {
+	for _, mediaType := range parseAccept(accept) {
+		if mediaType == "*/*" {
+			return n.Default
+		}
+		for _, mr := range n.renderers {
+			if mr.mediaType == mediaType {
+				return mr.renderer
+			}
+			// e.g. "application/*"
+			if strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(mr.mediaType, strings.TrimSuffix(mediaType, "*")) {
+				return mr.renderer
+			}
+		}
+	}
+	return n.Default
+}
+
+// parseAccept returns the media types in the Accept header ordered by the quality values.
+// media types of "q=0" are excluded.
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	ranges := []mediaRange{}
+	for _, s := range strings.Split(accept, ",") {
+		parts := strings.Split(s, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+		if len(mediaType) == 0 {
+			continue
+		}
+		q := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	mediaTypes := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		mediaTypes = append(mediaTypes, r.mediaType)
+	}
+	return mediaTypes
+}
+
+// defaultRenderer renders JSON, or XML when preferred by the Accept header
+func defaultRenderer() Renderer {
+	return NewNegotiateRenderer(JSONRenderer{}).
+		Register("application/json", JSONRenderer{}).
+		Register("application/xml", XMLRenderer{}).
+		Register("text/xml", XMLRenderer{})
+}
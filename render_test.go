@@ -0,0 +1,59 @@
+package router
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	cases := []struct {
+		input  string
+		expect []string
+	}{
+		{"", []string{}},
+		{"application/json", []string{"application/json"}},
+		{"text/html, application/XML;q=0.9, */*;q=0.8", []string{"text/html", "application/xml", "*/*"}},
+		{"application/json;q=0.5, application/xml", []string{"application/xml", "application/json"}},
+		{"application/json;q=0, text/xml", []string{"text/xml"}},
+	}
+	for i, c := range cases {
+		if result := parseAccept(c.input); !reflect.DeepEqual(result, c.expect) {
+			t.Errorf("#%d: want:%v, got:%v", i, c.expect, result)
+		}
+	}
+}
+
+func TestDefaultRenderer(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id" xml:"id"`
+		Name string `json:"name" xml:"name"`
+	}
+
+	cases := []struct {
+		inputAccept       string
+		expectContentType string
+		expectBody        string
+	}{
+		{"", "application/json; charset=utf-8", `{"id":1,"name":"foo"}`},
+		{"*/*", "application/json; charset=utf-8", `{"id":1,"name":"foo"}`},
+		{"text/html", "application/json; charset=utf-8", `{"id":1,"name":"foo"}`},
+		{"application/xml", "application/xml; charset=utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<user><id>1</id><name>foo</name></user>`},
+		{"text/*", "application/xml; charset=utf-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<user><id>1</id><name>foo</name></user>`},
+		{"application/xml;q=0.5, application/json", "application/json; charset=utf-8", `{"id":1,"name":"foo"}`},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", c.inputAccept)
+		if err := defaultRenderer().Render(w, req, user{ID: 1, Name: "foo"}); err != nil {
+			t.Fatalf("#%d: want no error, got %v", i, err)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != c.expectContentType {
+			t.Errorf("#%d: want Content-Type:%s, got Content-Type:%s", i, c.expectContentType, ct)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%s, got body:%s", i, c.expectBody, body)
+		}
+	}
+}
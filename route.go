@@ -1,11 +1,35 @@
 package router
 
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 // Route is represented one URL pass route
 type Route struct {
 	// called on request(ServeHTTP). behavior like http.handler
 	method  string
 	path    string
+	name    string
 	handler baseHandler
+	plan    *handlerPlan
+	group   *Group
+	// applied inside the group middlewares
+	middlewares []Middleware
+}
+
+// routePlan returns the precompiled plan of the handler registered via Router, nil otherwise.
+// the plan is cached on the routing tree node, and returned with HandlerData on lookup.
+func routePlan(h baseHandler) *handlerPlan {
+	if route, ok := h.(*Route); ok {
+		return route.plan
+	}
+	return nil
 }
 
 // HandleFunc register handler to route
@@ -15,3 +39,91 @@ func (r *Route) HandleFunc(method, path string, h baseHandler) *Route {
 	r.handler = h
 	return r
 }
+
+// Name set the name of the route, used by Router.URL
+func (r *Route) Name(name string) *Route {
+	r.name = name
+	return r
+}
+
+// Use appends middlewares applied to the route only
+func (r *Route) Use(mw ...Middleware) *Route {
+	r.middlewares = append(r.middlewares, mw...)
+	return r
+}
+
+// GetName returns the name of the route
+func (r *Route) GetName() string { return r.name }
+
+// GetMethod returns the HTTP method of the route
+func (r *Route) GetMethod() string { return r.method }
+
+// GetPath returns the registered path of the route. e.g. "/user/:id"
+func (r *Route) GetPath() string { return r.path }
+
+// wrap applies middlewares of the route and the route groups to h, the outermost group is applied first
+func (r *Route) wrap(h http.Handler) http.Handler {
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	for g := r.group; g != nil; g = g.parent {
+		for i := len(g.middlewares) - 1; i >= 0; i-- {
+			h = g.middlewares[i](h)
+		}
+	}
+	return h
+}
+
+// URL returns the URL path built from the route path and params.
+// params are filled into the ":param" and "*wildcard" segments in order.
+// ":param" is must not be empty, the empty "*wildcard" is built without the last "/", e.g. "/files" of "/files/*filepath".
+func (r *Route) URL(params ...interface{}) (string, error) {
+	parts, err := generateSplitPath(r.path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed build URL. path=%s", r.path)
+	}
+
+	var buf bytes.Buffer
+	n := 0
+	for _, p := range parts[1:] {
+		buf.WriteString("/")
+		if len(p) == 0 || !(isParamKey(p) || isWildcardKey(p)) {
+			buf.WriteString(p)
+			continue
+		}
+
+		if n >= len(params) {
+			return "", errors.Wrapf(ErrInvalidURLParams, "missing param %s. path=%s", p, r.path)
+		}
+		v := fmt.Sprint(params[n])
+		n++
+		if isWildcardKey(p) {
+			v = strings.TrimPrefix(v, "/")
+			if len(v) == 0 {
+				buf.Truncate(buf.Len() - 1)
+				continue
+			}
+		}
+		if len(v) == 0 {
+			return "", errors.Wrapf(ErrInvalidURLParams, "empty param %s. path=%s", p, r.path)
+		}
+		if isParamKey(p) {
+			buf.WriteString(url.PathEscape(v))
+			continue
+		}
+		// wildcard keeps "/" separators
+		ss := strings.Split(v, "/")
+		for i, s := range ss {
+			ss[i] = url.PathEscape(s)
+		}
+		buf.WriteString(strings.Join(ss, "/"))
+	}
+	if n != len(params) {
+		return "", errors.Wrapf(ErrInvalidURLParams, "too many params. want %d, got %d. path=%s", n, len(params), r.path)
+	}
+
+	if buf.Len() == 0 {
+		return "/", nil
+	}
+	return buf.String(), nil
+}
@@ -1,15 +1,19 @@
 package router
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"reflect"
 	"runtime"
-	"strconv"
+	"runtime/debug"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,9 +35,17 @@ const (
 
 // Errors
 var (
-	ErrNotFoundHandler = errors.New("not found matched handler")
-	ErrInvalidHandler  = errors.New("invalid handler")
-	ErrInvalidParam    = errors.New("invalid param")
+	ErrNotFoundHandler  = errors.New("not found matched handler")
+	ErrInvalidHandler   = errors.New("invalid handler")
+	ErrInvalidParam     = errors.New("invalid param")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+
+	ErrInvalidBody          = errors.New("invalid body")
+	ErrBodyTooLarge         = errors.New("body too large")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+	ErrNotFoundRouteName = errors.New("not found named route")
+	ErrInvalidURLParams  = errors.New("invalid URL params")
 )
 
 // Routing is represents routing tree
@@ -42,10 +54,21 @@ type Routing interface {
 	Insert(method, path string, handler baseHandler) error
 }
 
+// allowedMethodsRouting is implemented by Routing returning the methods matching path at once, e.g. Trie and RadixTree
+type allowedMethodsRouting interface {
+	AllowedMethods(path string) []string
+}
+
 // HandlerData is represents handler function and args
 type HandlerData struct {
 	handler baseHandler
 	params  []interface{}
+	// matched route pattern, e.g. "/user/:id"
+	pattern string
+	// looked up path without the query string, the params are extracted via appendParams when params is nil
+	path string
+	// precompiled on registration
+	plan *handlerPlan
 }
 
 // ValidationParam is customize validation parameter for the baseHandler
@@ -53,27 +76,150 @@ type ValidationParam interface {
 	Validate(raw string) bool
 }
 
+// ParamValidator is customize validation parameter for the baseHandler with the reason of failure.
+// the returned error is responded to the client as 400 Bad Request.
+// implemented by pointer receiver, the handler arg can be either pointer or value.
+type ParamValidator interface {
+	ValidateParam(raw string) error
+}
+
+// ValidationBody is customize validation for the request body bound to the baseHandler.
+// the returned error is responded to the client as 400 Bad Request.
+type ValidationBody interface {
+	ValidateBody() error
+}
+
+// ParamError is represents failure of converting or validating the path parameter
+type ParamError struct {
+	// path parameter name. e.g. "id" of "/user/:id"
+	Name string
+	Raw  string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: param=%s, raw=%s, error=%v", ErrInvalidParam, e.Name, e.Raw, e.Err)
+}
+
+// Cause returns ErrInvalidParam, for compatibility with errors.Cause
+func (e *ParamError) Cause() error { return ErrInvalidParam }
+
+// Unwrap returns the reason of failure
+func (e *ParamError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrInvalidParam, for errors.Is
+func (e *ParamError) Is(target error) bool { return target == ErrInvalidParam }
+
+// BodyError is represents failure of decoding or validating the request body
+type BodyError struct {
+	ContentType string
+	Err         error
+}
+
+func (e *BodyError) Error() string {
+	return fmt.Sprintf("%s: content-type=%s, error=%v", ErrInvalidBody, e.ContentType, e.Err)
+}
+
+// Cause returns ErrInvalidBody, for compatibility with errors.Cause
+func (e *BodyError) Cause() error { return ErrInvalidBody }
+
+// Unwrap returns the reason of failure
+func (e *BodyError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrInvalidBody, for errors.Is
+func (e *BodyError) Is(target error) bool { return target == ErrInvalidBody }
+
+// ErrorStatusCode returns the HTTP status code corresponding to err.
+//
+//	errors implemented StatusCode() int: the returned status code
+//	ErrNotFoundHandler, ErrPathNotFound: 404 Not Found
+//	ErrMethodNotAllowed: 405 Method Not Allowed
+//	ErrBodyTooLarge: 413 Request Entity Too Large
+//	ErrInvalidParam, ErrInvalidBody: 400 Bad Request
+//	ErrUnsupportedMediaType: 415 Unsupported Media Type
+//	others: 500 Internal Server Error
+func ErrorStatusCode(err error) int {
+	var sc interface {
+		StatusCode() int
+	}
+	if errors.As(err, &sc) {
+		return sc.StatusCode()
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFoundHandler), errors.Is(err, ErrPathNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+	case errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrInvalidParam), errors.Is(err, ErrInvalidBody):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 type baseHandler interface{}
 
 // Router is represents routing algorism and routes
 type Router struct {
 	NotFoundHandler http.Handler
-	Routing         Routing
-	routes          []*Route
-	outLog          *log.Logger
-	errLog          *log.Logger
+	// called when the path is matched other HTTP methods only.
+	// the "Allow" header is already set before called.
+	MethodNotAllowedHandler http.Handler
+	// called when failed to route or call the matched handler, e.g. ErrPathNotFound, ErrMethodNotAllowed
+	// and *ParamError from the invalid params. the "Allow" header is already set for ErrMethodNotAllowed.
+	// errors are comparable with errors.Is and errors.As. see ErrorStatusCode for default behavior.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, err error)
+	// render the values returned from the handlers, e.g. func(req *http.Request) (*User, error).
+	// default chooses JSON or XML by the Accept header.
+	Renderer Renderer
+	// recover panics in the handlers, middlewares and the params conversion
+	RecoverPanic bool
+	// called with the recovered value when RecoverPanic is enabled.
+	// default logs the stack trace and responds 500 Internal Server Error.
+	PanicHandler func(w http.ResponseWriter, req *http.Request, rcv interface{})
+	// answer OPTIONS requests with the "Allow" header when not registered OPTIONS handler
+	AutoOptions bool
+	// answer HEAD requests via GET handler when not registered HEAD handler
+	AutoHead bool
+	// limit of the request body decoded into the handler args, 0 is unlimited.
+	// exceeded body responds 413 Request Entity Too Large.
+	MaxBodyBytes int64
+	Routing      Routing
+	routes       []*Route
+	// applied to the all routes after routing
+	middlewares []Middleware
+	// used in preference to the builtin conversions
+	converters map[reflect.Type]Converter
+	outLog     *log.Logger
+	errLog     *log.Logger
 }
 
 // NewRouter return created Router
 func NewRouter() *Router {
 	return &Router{
-		NotFoundHandler: http.NotFoundHandler(),
-		Routing:         NewTrie(),
-		outLog:          newLogger(os.Stdout),
-		errLog:          newLogger(os.Stderr),
+		NotFoundHandler:         http.NotFoundHandler(),
+		MethodNotAllowedHandler: methodNotAllowedHandler(),
+		AutoOptions:             true,
+		AutoHead:                true,
+		MaxBodyBytes:            defaultMaxBodyBytes,
+		Renderer:                defaultRenderer(),
+		Routing:                 NewRadixTree(),
+		outLog:                  newLogger(os.Stdout),
+		errLog:                  newLogger(os.Stderr),
 	}
 }
 
+func methodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+	})
+}
+
 func newLogger(w io.Writer) *log.Logger {
 	return log.New(w, "", log.LstdFlags|log.Lshortfile)
 }
@@ -94,6 +240,13 @@ func (r *Router) accessLogf(format string, args ...interface{}) {
 	}
 }
 
+// logAccess avoids formatting the access log when logging is disabled
+func (r *Router) logAccess(req *http.Request) {
+	if env := os.Getenv("GO_ROUTER_ENABLE_LOGGING"); len(env) != 0 {
+		r.accessLogf("%s - - [%s] \"%s %s %s\"", req.RemoteAddr, time.Now().Format(time.RFC822Z), req.Method, req.URL.Path, req.Proto)
+	}
+}
+
 func (r *Router) errorLogf(format string, args ...interface{}) {
 	if env := os.Getenv("GO_ROUTER_ENABLE_LOGGING"); len(env) != 0 {
 		r.errLog.Printf("[error] %s\n", args)
@@ -101,76 +254,292 @@ func (r *Router) errorLogf(format string, args ...interface{}) {
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	hd, err := r.Routing.Lookup(req.URL.Path, req.Method)
-	if err != nil {
-		r.errorLogf("not found path: %s. %#v", req.URL.Path, err)
-		r.NotFoundHandler.ServeHTTP(w, req)
+	if r.RecoverPanic {
+		// req refers to the request with the matched route after routing
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				r.handlePanic(w, req, rcv)
+			}
+		}()
+	}
+
+	ps := getPathParams()
+	defer putPathParams(ps)
+	hd, ok := r.lookup(req.Method, req.URL.Path, ps)
+	if !ok && req.Method == http.MethodHead && r.AutoHead {
+		// fallback to GET handler without response body
+		if hd, ok = r.lookup(http.MethodGet, req.URL.Path, ps); ok {
+			w = &headResponseWriter{ResponseWriter: w}
+		}
+	}
+	if !ok {
+		if allowed := r.allowedMethods(req.URL.Path); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			if req.Method == http.MethodOptions && r.AutoOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			r.errorLogf("not allowed method: %s %s", req.Method, req.URL.Path)
+			r.handleError(w, req, errors.Wrapf(ErrMethodNotAllowed, "method=%s, path=%s", req.Method, req.URL.Path))
+			return
+		}
+		r.errorLogf("not found path: %s", req.URL.Path)
+		r.handleError(w, req, errors.Wrapf(ErrPathNotFound, "path=%s", req.URL.Path))
 		return
 	}
 
-	err = r.callHandler(w, req, hd)
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err := r.callHandler(w, req, hd, *ps)
+		if err != nil {
+			r.errorLogf("failed call handler. %#v", err)
+			r.handleError(w, req, err)
+		}
+	})
+	match := &routeMatch{pattern: hd.pattern}
+	if len(*ps) != 0 {
+		match.params = *ps
+	}
+	if route, ok := hd.handler.(*Route); ok {
+		hd.handler = route.handler
+		if hd.plan == nil {
+			hd.plan = route.plan
+		}
+		h = route.wrap(h)
+		match.route = route
+		match.pattern = route.path
+	}
+	req = req.WithContext(context.WithValue(req.Context(), routeContextKey, match))
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	h.ServeHTTP(w, req)
+}
+
+// paramsRouting is implemented by Routing storing the path parameters without allocation, e.g. Trie and RadixTree
+type paramsRouting interface {
+	LookupParams(method, path string, ps *PathParams) (HandlerData, bool)
+}
+
+// lookup returns the HandlerData matching method and path, and stores the path parameters to ps
+func (r *Router) lookup(method, path string, ps *PathParams) (HandlerData, bool) {
+	if pr, ok := r.Routing.(paramsRouting); ok {
+		return pr.LookupParams(method, path, ps)
+	}
+
+	hd, err := r.Routing.Lookup(path, method)
 	if err != nil {
-		r.errorLogf("failed call handler. %#v", err)
+		return HandlerData{}, false
+	}
+	if route, ok := hd.handler.(*Route); ok && len(hd.pattern) == 0 {
+		hd.pattern = route.path
+	}
+	*ps = (*ps)[:0]
+	hd.appendParams(ps)
+	return hd, true
+}
+
+func (r *Router) handlePanic(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+	// keep the behavior of net/http for aborting the response
+	if rcv == http.ErrAbortHandler {
+		panic(rcv)
+	}
+	if r.PanicHandler != nil {
+		r.PanicHandler(w, req, rcv)
+		return
+	}
+
+	pattern := ""
+	if route := CurrentRoute(req); route != nil {
+		pattern = route.path
+	}
+	r.errLog.Printf("[panic] %v. method=%s, path=%s, route=%s\n%s", rcv, req.Method, req.URL.Path, pattern, debug.Stack())
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (r *Router) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	if r.ErrorHandler != nil {
+		r.ErrorHandler(w, req, err)
+		return
+	}
+	r.defaultErrorHandler(w, req, err)
+}
+
+// defaultErrorHandler responds the status code via ErrorStatusCode.
+// 404 is delegated to NotFoundHandler, 405 is delegated to MethodNotAllowedHandler, and 400 responds the stable message built from the ParamError name,
+// e.g. "invalid param: id", or "invalid body". the internal causes such as strconv or json errors are not responded, see errorLogf.
+func (r *Router) defaultErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	code := ErrorStatusCode(err)
+	switch code {
+	case http.StatusNotFound:
 		r.NotFoundHandler.ServeHTTP(w, req)
 		return
+	case http.StatusMethodNotAllowed:
+		r.MethodNotAllowedHandler.ServeHTTP(w, req)
+		return
 	}
+
+	var be *BodyError
+	if code == http.StatusBadRequest && errors.As(err, &be) {
+		http.Error(w, withReason(ErrInvalidBody.Error(), be.Err), code)
+		return
+	}
+	var pe *ParamError
+	if code == http.StatusBadRequest && errors.As(err, &pe) {
+		http.Error(w, withReason(fmt.Sprintf("%s: %s", ErrInvalidParam, pe.Name), pe.Err), code)
+		return
+	}
+	http.Error(w, http.StatusText(code), code)
 }
 
-func (r *Router) callHandler(w http.ResponseWriter, req *http.Request, hd HandlerData) error {
-	ref := reflect.ValueOf(hd.handler)
-	if ref.Kind() != reflect.Func {
-		return errors.Wrapf(ErrInvalidHandler, "handler is must be Func. got:%v", ref.Kind())
+// withReason appends the reason of failure responded to the client to msg.
+// the reason is the error from ParamValidator, ValidationBody or the missing required key, the others are not appended.
+func withReason(msg string, err error) string {
+	var ve *validationError
+	switch {
+	case errors.As(err, &ve):
+		return msg + ": " + ve.Error()
+	case errors.Is(err, errRequiredParam):
+		return msg + ": " + errRequiredParam.Error()
+	}
+	return msg
+}
+
+// routingAllowedMethods returns the sorted methods have a route matching path.
+// looks up each of mountMethods when Routing does not implement allowedMethodsRouting.
+func (r *Router) routingAllowedMethods(path string) []string {
+	if ar, ok := r.Routing.(allowedMethodsRouting); ok {
+		return ar.AllowedMethods(path)
+	}
+	methods := []string{}
+	for _, method := range mountMethods {
+		if _, err := r.Routing.Lookup(path, method); err == nil {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// allowedMethods returns methods for the "Allow" header, including automatic HEAD and OPTIONS
+func (r *Router) allowedMethods(path string) []string {
+	methods := r.routingAllowedMethods(path)
+	if len(methods) == 0 {
+		return methods
+	}
+
+	var hasGet, hasHead, hasOptions bool
+	for _, m := range methods {
+		switch m {
+		case http.MethodGet:
+			hasGet = true
+		case http.MethodHead:
+			hasHead = true
+		case http.MethodOptions:
+			hasOptions = true
+		}
+	}
+	if r.AutoHead && hasGet && !hasHead {
+		methods = append(methods, http.MethodHead)
+	}
+	if r.AutoOptions && !hasOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// headResponseWriter discards response body for HEAD requests.
+// the optional interfaces of the wrapped writer are forwarded, and Unwrap is used by http.ResponseController.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+// Unwrap returns the wrapped writer
+func (w *headResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush flushes the wrapped writer when implemented http.Flusher
+func (w *headResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection of the wrapped writer, returns error when not implemented http.Hijacker
+func (w *headResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, errors.Errorf("not implemented http.Hijacker. got:%T", w.ResponseWriter)
+}
+
+func (r *Router) callHandler(w http.ResponseWriter, req *http.Request, hd HandlerData, ps PathParams) error {
+	plan, err := r.handlerPlan(hd)
+	if err != nil {
+		return err
+	}
+	if plan.direct != nil && len(ps) == 0 {
+		r.logAccess(req)
+		plan.direct(w, req)
+		return nil
+	}
+	if plan.typed != nil {
+		r.logAccess(req)
+		return plan.typed(w, req, ps)
+	}
+
+	if plan.hasBody && r.MaxBodyBytes > 0 {
+		req.Body = newLimitedBody(req.Body, r.MaxBodyBytes)
 	}
-	args, err := r.parseParams(w, req, hd)
+	args, err := plan.args(w, req, ps)
 	if err != nil {
 		return errors.Wrapf(err, "failed parsed params")
 	}
+	defer plan.release(args)
 
-	r.accessLogf("%s - - [%s] \"%s %s %s\"", req.RemoteAddr, time.Now().Format(time.RFC822Z), req.Method, req.URL.Path, req.Proto)
-	ref.Call(args)
+	r.logAccess(req)
+	v, err := plan.result(plan.fn.Call(*args))
+	if err != nil {
+		return err
+	}
+	if v.IsValid() {
+		return r.render(w, req, v)
+	}
 	return nil
 }
 
-// params convert to []reflect.Value
-func (r *Router) parseParams(w http.ResponseWriter, req *http.Request, hd HandlerData) ([]reflect.Value, error) {
-	// static args means http.ResponseWriter and *http.Request
-	numStaticArgs := 2
-
-	ref := reflect.TypeOf(hd.handler)
-	if ref.NumIn() != len(hd.params)+numStaticArgs {
-		return nil, errors.Wrapf(ErrNotFoundHandler, "path=%s, handler=%v", req.URL.Path, hd.handler)
+// render writes the value returned from the handler via Renderer. nil value responds 204 No Content.
+func (r *Router) render(w http.ResponseWriter, req *http.Request, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+	if err := r.Renderer.Render(w, req, v.Interface()); err != nil {
+		return errors.Wrapf(err, "failed render. path=%s", req.URL.Path)
 	}
+	return nil
+}
 
-	// static args
-	args := []reflect.Value{
-		reflect.ValueOf(w),
-		reflect.ValueOf(req),
+// handlerPlan returns the registered plan, or analyzes the handler when registered without Router
+func (r *Router) handlerPlan(hd HandlerData) (*handlerPlan, error) {
+	if hd.plan != nil {
+		return hd.plan, nil
 	}
-	// dynamic args
-	for i := numStaticArgs; i < ref.NumIn(); i++ {
-		param := hd.params[i-numStaticArgs]
-		t := ref.In(i)
-		switch t.Kind() {
-		case reflect.Int:
-			p, err := strconv.Atoi(param.(string))
-			if err != nil {
-				return nil, errors.Wrapf(ErrInvalidParam, "path=%s, error=%s", req.URL.Path, err)
-			}
-			args = append(args, reflect.ValueOf(p))
-		case reflect.Ptr:
-			v, ok := reflect.New(t.Elem()).Interface().(ValidationParam)
-			if !ok {
-				return nil, errors.Wrapf(ErrInvalidParam, "Validatable parameters only allow ValidationParam, path=%s", req.URL.Path)
-			}
-			if !v.Validate(hd.params[i-numStaticArgs].(string)) {
-				return nil, errors.Wrapf(ErrInvalidParam, "Failed to validation, path=%s", req.URL.Path)
-			}
-			args = append(args, reflect.ValueOf(v))
-		default:
-			args = append(args, reflect.ValueOf(param))
-		}
+	ref := reflect.ValueOf(hd.handler)
+	if ref.Kind() != reflect.Func {
+		return nil, errors.Wrapf(ErrInvalidHandler, "handler is must be Func. got:%v", ref.Kind())
 	}
-	return args, nil
+	return newHandlerPlan(hd.handler, nil, nil, r.converter, r.registered), nil
+}
+
+// Use appends middlewares applied to the all routes.
+// middlewares are called after routing, thereby can refer the matched route via CurrentRoute.
+func (r *Router) Use(mw ...Middleware) {
+	r.middlewares = append(r.middlewares, mw...)
 }
 
 // Get register handler via GET
@@ -194,34 +563,150 @@ func (r *Router) Delete(path string, h baseHandler) *Route { return r.HandleFunc
 // Options register handler via OPTIONS
 func (r *Router) Options(path string, h baseHandler) *Route { return r.HandleFunc("OPTIONS", path, h) }
 
-// HandleFunc register handler each HTTP method
+// HandleFunc register handler each HTTP method.
+// the handler is not registered when invalid, see Handle.
 func (r *Router) HandleFunc(method, path string, h baseHandler) *Route {
-	route := r.AddRoute().HandleFunc(method, path, h)
-	err := r.Routing.Insert(route.method, route.path, route.handler)
+	route, err := r.Handle(method, path, h)
+	return r.registeredRoute(method, path, h, route, err)
+}
+
+// registeredRoute returns the route, or logs the error of registration
+func (r *Router) registeredRoute(method, path string, h baseHandler, route *Route, err error) *Route {
 	if err != nil {
 		r.errorLogf("failed registered path. path=%s, error=%v", path, err)
+		// keep the method chain available with unregistered route
+		return (&Route{}).HandleFunc(method, path, h)
+	}
+	return route
+}
+
+// Handle register handler each HTTP method.
+// returns error when the handler signature does not match the path, or the path is already registered.
+func (r *Router) Handle(method, path string, h baseHandler) (*Route, error) {
+	if err := validateHandler(path, h, r.converter, r.registered); err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+	names, _ := paramNames(path)
+	types, _ := paramSegmentTypes(path)
+	return r.handlePlan(method, path, h, newHandlerPlan(h, names, types, r.converter, r.registered))
+}
+
+// handlePlan registers the route called via the plan
+func (r *Router) handlePlan(method, path string, h baseHandler, plan *handlerPlan) (*Route, error) {
+	route := (&Route{}).HandleFunc(method, path, h)
+	route.plan = plan
+	if err := r.Routing.Insert(route.method, route.path, route); err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+	r.routes = append(r.routes, route)
+	return route, nil
+}
+
+// MustHandle is like Handle but panics when failed to register
+func (r *Router) MustHandle(method, path string, h baseHandler) *Route {
+	route, err := r.Handle(method, path, h)
+	if err != nil {
+		panic(err)
 	}
 	return route
 }
 
 // ServeDir register handler for static directories
 func (r *Router) ServeDir(path string, root http.FileSystem) {
+	r.Get(path, serveDirHandler(root))
+}
+
+// ServeFile register handler for static files
+func (r *Router) ServeFile(path string, file string) {
+	r.Get(path, serveFileHandler(file))
+}
+
+// Mount register h for the all requests under the prefix.
+// h receives the request path stripped the prefix from both Path and RawPath, and the original path via OriginalPath.
+func (r *Router) Mount(prefix string, h http.Handler) {
+	prefix = joinPath("", prefix)
+	exact, sub := mountHandlers(prefix, h)
+	for _, method := range mountMethods {
+		r.HandleFunc(method, prefix, exact)
+		r.HandleFunc(method, joinPath(prefix, "/*path"), sub)
+	}
+}
+
+// refers to: net/http/method.go
+var mountMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// mountHandlers returns handlers for the prefix itself and the paths under the prefix.
+// the prefix is stripped from both Path and RawPath like http.StripPrefix,
+// responds 404 when the escaped path does not begin with the prefix.
+func mountHandlers(prefix string, h http.Handler) (baseHandler, baseHandler) {
+	serve := func(w http.ResponseWriter, req *http.Request) {
+		path := strings.TrimPrefix(req.URL.Path, prefix)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		rawPath := req.URL.RawPath
+		if len(rawPath) != 0 {
+			if !hasPathPrefix(rawPath, prefix) {
+				http.NotFound(w, req)
+				return
+			}
+			rawPath = strings.TrimPrefix(rawPath, prefix)
+			if !strings.HasPrefix(rawPath, "/") {
+				rawPath = "/" + rawPath
+			}
+		}
+
+		ctx := req.Context()
+		if _, ok := ctx.Value(originalPathContextKey).(string); !ok {
+			ctx = context.WithValue(ctx, originalPathContextKey, req.URL.Path)
+		}
+		req = req.WithContext(ctx)
+		u := *req.URL
+		u.Path = path
+		u.RawPath = rawPath
+		req.URL = &u
+		h.ServeHTTP(w, req)
+	}
+	exact := func(w http.ResponseWriter, req *http.Request) { serve(w, req) }
+	sub := func(w http.ResponseWriter, req *http.Request, _ string) { serve(w, req) }
+	return exact, sub
+}
+
+// hasPathPrefix reports whether path begins with the segments of prefix, e.g. "/a/b" begins with "/a", "/ab" does not
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	rest := path[len(prefix):]
+	return len(rest) == 0 || rest[0] == '/' || strings.HasSuffix(prefix, "/")
+}
+
+func serveDirHandler(root http.FileSystem) baseHandler {
 	fs := http.FileServer(root)
-	r.Get(path, func(w http.ResponseWriter, req *http.Request, suffixPath string) {
+	return func(w http.ResponseWriter, req *http.Request, suffixPath string) {
 		req.URL.Path = suffixPath
 		fs.ServeHTTP(w, req)
-	})
+	}
 }
 
-// ServeFile register handler for static files
-func (r *Router) ServeFile(path string, file string) {
-	r.Get(path, func(w http.ResponseWriter, req *http.Request) {
+func serveFileHandler(file string) baseHandler {
+	return func(w http.ResponseWriter, req *http.Request) {
 		if containsDotDot(file) {
 			http.Error(w, "invalid URL path", http.StatusBadRequest)
 			return
 		}
 		http.ServeFile(w, req, file)
-	})
+	}
 }
 
 func containsDotDot(v string) bool {
@@ -245,6 +730,16 @@ func (r *Router) AddRoute() *Route {
 	return route
 }
 
+// URL returns the URL path of the named route filled with params
+func (r *Router) URL(name string, params ...interface{}) (string, error) {
+	for _, route := range r.routes {
+		if route.name == name {
+			return route.URL(params...)
+		}
+	}
+	return "", errors.Wrapf(ErrNotFoundRouteName, "name=%s", name)
+}
+
 // PrintRoutes display all registered routes
 func (r *Router) PrintRoutes(w io.Writer) {
 	routes := r.routes
@@ -2,6 +2,7 @@ package router
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -44,7 +46,19 @@ func printValues(vs []reflect.Value) {
 	}
 }
 
-func TestParseParams(t *testing.T) {
+// valuesToPathParams returns the path parameters of the values without the keys
+func valuesToPathParams(values []interface{}) PathParams {
+	if len(values) == 0 {
+		return nil
+	}
+	ps := make(PathParams, len(values))
+	for i, v := range values {
+		ps[i].Value, _ = v.(string)
+	}
+	return ps
+}
+
+func TestHandlerPlanArgs(t *testing.T) {
 	type invalidValidationParam struct{}
 
 	cases := []struct {
@@ -104,14 +118,19 @@ func TestParseParams(t *testing.T) {
 	for i, c := range cases {
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("GET", "/", nil)
-		router := NewRouter()
-		result, err := router.parseParams(w, r, c.input)
+		plan, err := NewRouter().handlerPlan(c.input)
+		if err != nil {
+			t.Fatalf("#%d: want no error, got %v", i, err)
+		}
+		args, err := plan.args(w, r, valuesToPathParams(c.input.params))
 		if errors.Cause(err) != c.expectError {
 			t.Errorf("#%d: want error:%#v , got error:%#v ", i, c.expectError, err)
 		}
 		if err != nil {
 			continue
 		}
+		result := append([]reflect.Value{}, *args...)
+		plan.release(args)
 
 		// complement a missing variable
 		c.expectValues = append(c.expectValues, reflect.ValueOf(w))
@@ -161,7 +180,7 @@ func TestCallHandler(t *testing.T) {
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("GET", "/", nil)
 		router := NewRouter()
-		err := router.callHandler(w, r, c.input)
+		err := router.callHandler(w, r, c.input, valuesToPathParams(c.input.params))
 		if errors.Cause(err) != c.expect {
 			t.Errorf("#%d: want error:%#v , got error:%#v ", i, c.expect, err)
 		}
@@ -201,6 +220,22 @@ func TestServeHTTP(t *testing.T) {
 			"id=10, name=hoge",
 			200,
 		},
+		{
+			"/dummy/:id<[0-9]+>/dummy/{name:[a-z]+}",
+			dummyHandlerWithParams,
+			"GET",
+			"/dummy/10/dummy/hoge",
+			"id=10, name=hoge",
+			200,
+		},
+		{
+			"/dummy/:id<[0-9]+>/dummy/{name:[a-z]+}",
+			dummyHandlerWithParams,
+			"GET",
+			"/dummy/notint/dummy/hoge",
+			"404 page not found\n",
+			404,
+		},
 		{
 			"/",
 			func(w http.ResponseWriter, req *http.Request) { fmt.Fprintf(w, "from post") },
@@ -222,8 +257,8 @@ func TestServeHTTP(t *testing.T) {
 			func(w http.ResponseWriter, req *http.Request, id int) {},
 			"GET",
 			"/dummy/notint",
-			"404 page not found\n", // http.NotFoundHandler used fmt.Fprintln()
-			404,
+			"invalid param: id\n",
+			400,
 		},
 		{
 			"/:id",
@@ -238,8 +273,8 @@ func TestServeHTTP(t *testing.T) {
 			dummyHandlerWithValidationParams,
 			"GET",
 			"/1000",
-			"404 page not found\n", // http.NotFoundHandler used fmt.Fprintln()
-			404,
+			"invalid param: id\n",
+			400,
 		},
 		{
 			"/:id",
@@ -549,3 +584,829 @@ func TestPrintRoutes(t *testing.T) {
 		t.Errorf("want:\n%s\ngot:\n%s", want, buf.String())
 	}
 }
+
+func TestServeHTTPWithMethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+	r.Put("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+	r.Post("/user", dummyHandler)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	cases := []struct {
+		inputMethod  string
+		inputPath    string
+		expectStatus int
+		expectAllow  string
+	}{
+		{"GET", "/user/10", 200, ""},
+		{"POST", "/user/10", 405, "GET, HEAD, OPTIONS, PUT"},
+		{"DELETE", "/user/10", 405, "GET, HEAD, OPTIONS, PUT"},
+		{"GET", "/user", 405, "OPTIONS, POST"},
+		{"GET", "/none", 404, ""},
+	}
+	for i, c := range cases {
+		req, err := http.NewRequest(c.inputMethod, ts.URL+c.inputPath, nil)
+		if err != nil {
+			t.Errorf("#%d: want no error, got %v", i, err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("#%d: want no error, got %v", i, err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, res.StatusCode)
+		}
+		if allow := res.Header.Get("Allow"); allow != c.expectAllow {
+			t.Errorf("#%d: want Allow:%q, got Allow:%q", i, c.expectAllow, allow)
+		}
+	}
+}
+
+// lookupOnlyRouting is the Routing implemented only Lookup and Insert, delegating to the embedded Routing
+type lookupOnlyRouting struct {
+	routing Routing
+}
+
+func (r *lookupOnlyRouting) Lookup(path, method string) (HandlerData, error) {
+	return r.routing.Lookup(path, method)
+}
+
+func (r *lookupOnlyRouting) Insert(method, path string, handler baseHandler) error {
+	return r.routing.Insert(method, path, handler)
+}
+
+func TestServeHTTPWithLookupOnlyRouting(t *testing.T) {
+	r := NewRouter()
+	r.Routing = &lookupOnlyRouting{routing: NewTrie()}
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+	r.Put("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+	r.Post("/user", dummyHandler)
+
+	cases := []struct {
+		inputMethod  string
+		inputPath    string
+		expectStatus int
+		expectAllow  string
+	}{
+		{"GET", "/user/10", 200, ""},
+		{"POST", "/user/10", 405, "GET, HEAD, OPTIONS, PUT"},
+		{"GET", "/user", 405, "OPTIONS, POST"},
+		{"OPTIONS", "/user/10", 204, "GET, HEAD, OPTIONS, PUT"},
+		{"GET", "/none", 404, ""},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.inputMethod, c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != c.expectAllow {
+			t.Errorf("#%d: want Allow:%q, got Allow:%q", i, c.expectAllow, allow)
+		}
+	}
+}
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	r := NewRouter()
+	r.Get("/", dummyHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "allow=%s", w.Header().Get("Allow"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("want status code:%d, got status code:%d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if body := w.Body.String(); body != "allow=GET, HEAD, OPTIONS" {
+		t.Errorf("want body:%s, got body:%s", "allow=GET, HEAD, OPTIONS", body)
+	}
+}
+
+func TestAutoOptionsAndHead(t *testing.T) {
+	cases := []struct {
+		autoOptions  bool
+		autoHead     bool
+		inputMethod  string
+		inputPath    string
+		expectStatus int
+		expectAllow  string
+		expectBody   string
+	}{
+		{true, true, "OPTIONS", "/user/10", 204, "GET, HEAD, OPTIONS, PUT", ""},
+		{true, true, "OPTIONS", "/explicit", 200, "", "explicit options"},
+		{true, true, "OPTIONS", "/none", 404, "", "404 page not found\n"},
+		{false, true, "OPTIONS", "/user/10", 405, "GET, HEAD, PUT", "405 method not allowed\n"},
+		{true, true, "HEAD", "/user/10", 200, "", ""},
+		{true, true, "HEAD", "/explicit", 200, "", "explicit head"},
+		{true, false, "HEAD", "/user/10", 405, "GET, OPTIONS, PUT", "405 method not allowed\n"},
+	}
+	for i, c := range cases {
+		r := NewRouter()
+		r.AutoOptions = c.autoOptions
+		r.AutoHead = c.autoHead
+		r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {
+			fmt.Fprint(w, "get body")
+		})
+		r.Put("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+		r.Options("/explicit", func(w http.ResponseWriter, req *http.Request) { fmt.Fprint(w, "explicit options") })
+		r.Head("/explicit", func(w http.ResponseWriter, req *http.Request) { fmt.Fprint(w, "explicit head") })
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.inputMethod, c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != c.expectAllow {
+			t.Errorf("#%d: want Allow:%q, got Allow:%q", i, c.expectAllow, allow)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestAutoHeadResponseWriter(t *testing.T) {
+	r := NewRouter()
+	r.Get("/stream", func(w http.ResponseWriter, req *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatalf("want http.Flusher, got %T", w)
+		}
+		fmt.Fprint(w, "chunk")
+		f.Flush()
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			t.Fatalf("want Unwrap, got %T", w)
+		}
+		if _, ok := u.Unwrap().(*httptest.ResponseRecorder); !ok {
+			t.Errorf("want unwrapped *httptest.ResponseRecorder, got %T", u.Unwrap())
+		}
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Errorf("want error from not hijackable writer, got nil")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/stream", nil))
+	if !w.Flushed {
+		t.Errorf("want flushed, got not flushed")
+	}
+	if body := w.Body.String(); body != "" {
+		t.Errorf("want empty body, got body:%q", body)
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := NewRouter()
+	r.Get("/", dummyHandler).Name("index")
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {}).Name("user")
+	r.Get("/user/:id/:name", dummyHandlerWithParams).Name("userName")
+	r.Get("/static/*filepath", func(w http.ResponseWriter, req *http.Request, path string) {}).Name("static")
+
+	cases := []struct {
+		name        string
+		params      []interface{}
+		expectURL   string
+		expectError error
+	}{
+		{"index", nil, "/", nil},
+		{"user", []interface{}{10}, "/user/10", nil},
+		{"userName", []interface{}{10, "foo bar/baz"}, "/user/10/foo%20bar%2Fbaz", nil},
+		{"static", []interface{}{"css/main file.css"}, "/static/css/main%20file.css", nil},
+		{"static", []interface{}{"/css/main.css"}, "/static/css/main.css", nil},
+		{"static", []interface{}{""}, "/static", nil},
+		{"static", []interface{}{"/"}, "/static", nil},
+		{"user", nil, "", ErrInvalidURLParams},
+		{"user", []interface{}{""}, "", ErrInvalidURLParams},
+		{"user", []interface{}{10, 20}, "", ErrInvalidURLParams},
+		{"index", []interface{}{10}, "", ErrInvalidURLParams},
+		{"none", nil, "", ErrNotFoundRouteName},
+	}
+	for i, c := range cases {
+		u, err := r.URL(c.name, c.params...)
+		if errors.Cause(err) != c.expectError {
+			t.Errorf("#%d: want error:%v, got error:%v", i, c.expectError, err)
+		}
+		if u != c.expectURL {
+			t.Errorf("#%d: want URL:%s, got URL:%s", i, c.expectURL, u)
+		}
+		if err != nil {
+			continue
+		}
+		// the built URL is routed to the route
+		hd, ok := r.lookup("GET", u, &PathParams{})
+		if route, _ := hd.handler.(*Route); !ok || route.name != c.name {
+			t.Errorf("#%d: want routed to %s, got found:%t, pattern:%s", i, c.name, ok, hd.pattern)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var called []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				route := CurrentRoute(req)
+				called = append(called, fmt.Sprintf("%s:%s %s:%s", name, route.GetMethod(), route.GetPath(), route.GetName()))
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	r := NewRouter()
+	r.Use(record("global1"), record("global2"))
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {
+		called = append(called, "handler")
+	}).Name("user").Use(record("route"))
+	r.Group("/api", func(g *Group) {
+		g.Use(record("group"))
+		g.Get("/", dummyHandler).Use(record("route"))
+	})
+
+	cases := []struct {
+		inputPath    string
+		expectCalled []string
+	}{
+		{
+			"/user/10",
+			[]string{"global1:GET /user/:id:user", "global2:GET /user/:id:user", "route:GET /user/:id:user", "handler"},
+		},
+		{
+			"/api",
+			[]string{"global1:GET /api:", "global2:GET /api:", "group:GET /api:", "route:GET /api:"},
+		},
+		{
+			"/none",
+			nil,
+		},
+	}
+	for i, c := range cases {
+		called = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", c.inputPath, nil))
+		if !reflect.DeepEqual(called, c.expectCalled) {
+			t.Errorf("#%d: want called:%v, got called:%v", i, c.expectCalled, called)
+		}
+	}
+
+	if route := CurrentRoute(httptest.NewRequest("GET", "/", nil)); route != nil {
+		t.Errorf("want nil route outside router, got %v", route)
+	}
+}
+
+func TestMount(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s original=%s", req.Method, req.URL.Path, OriginalPath(req))
+		if len(req.URL.RawPath) != 0 {
+			fmt.Fprintf(w, " raw=%s", req.URL.RawPath)
+		}
+	})
+	sub := NewRouter()
+	sub.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {
+		fmt.Fprintf(w, "sub id=%d original=%s", id, OriginalPath(req))
+	})
+	nested := NewRouter()
+	nested.Mount("/sub", sub)
+
+	r := NewRouter()
+	r.Get("/admin/static", dummyHandler)
+	r.Mount("/admin", echo)
+	r.Mount("/nested/", nested)
+	r.Group("/api", func(g *Group) {
+		g.Use(appendHeaderMiddleware("api"))
+		g.Mount("/echo", echo)
+	})
+
+	cases := []struct {
+		inputMethod  string
+		inputPath    string
+		expectStatus int
+		expectBody   string
+	}{
+		{"GET", "/admin", 200, "GET / original=/admin"},
+		{"POST", "/admin/", 200, "POST / original=/admin/"},
+		{"DELETE", "/admin/foo/bar/", 200, "DELETE /foo/bar/ original=/admin/foo/bar/"},
+		{"GET", "/admin/static", 200, "hello, world"},
+		{"GET", "/adminx", 404, "404 page not found\n"},
+		{"GET", "/nested/sub/user/10", 200, "sub id=10 original=/nested/sub/user/10"},
+		{"GET", "/nested/sub/none", 404, "404 page not found\n"},
+		{"PUT", "/api/echo/foo?q=1", 200, "PUT /foo original=/api/echo/foo"},
+		{"GET", "/admin/a%2Fb", 200, "GET /a/b original=/admin/a/b raw=/a%2Fb"},
+		{"GET", "/admin%2Fa/b", 404, "404 page not found\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.inputMethod, c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	r := NewRouter()
+	if _, err := r.Handle("GET", "/user/:id", dummyHandler); errors.Cause(err) != ErrInvalidHandler {
+		t.Errorf("want error:%v, got error:%v", ErrInvalidHandler, err)
+	}
+	if _, err := r.Handle("GET", "/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {}); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+	if _, err := r.Handle("GET", "/user/:name", func(w http.ResponseWriter, req *http.Request, name string) {}); errors.Cause(err) != ErrAlreadyPathRegistered {
+		t.Errorf("want error:%v, got error:%v", ErrAlreadyPathRegistered, err)
+	}
+	if len(r.routes) != 1 {
+		t.Errorf("want registered routes:1, got routes:%d", len(r.routes))
+	}
+
+	defer func() {
+		if rcv := recover(); rcv == nil {
+			t.Errorf("want panic from MustHandle")
+		}
+	}()
+	r.MustHandle("GET", "/:id/:name", dummyHandler)
+}
+
+func TestHandlerPlanOnNode(t *testing.T) {
+	for name, routing := range map[string]Routing{"Trie": NewTrie(), "RadixTree": NewRadixTree()} {
+		r := NewRouter()
+		r.Routing = routing
+		route := r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+
+		hd, err := r.Routing.Lookup("/user/10", "GET")
+		if err != nil {
+			t.Fatalf("%s: want no error, got %v", name, err)
+		}
+		if hd.plan == nil || hd.plan != route.plan {
+			t.Errorf("%s: want plan:%p, got plan:%p", name, route.plan, hd.plan)
+		}
+	}
+}
+
+func BenchmarkServeHTTPStatic(b *testing.B) {
+	r := NewRouter()
+	r.Get("/user/list", func(w http.ResponseWriter, req *http.Request) {})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/user/list", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTPParams(b *testing.B) {
+	r := NewRouter()
+	r.Get("/user/:id/:name", func(w http.ResponseWriter, req *http.Request, id int, name string) {})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/user/10/foo", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeHTTPValidationParam(b *testing.B) {
+	r := NewRouter()
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, v *dummyValidationParam) {})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/user/100", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func TestServeHTTPWithTypedParams(t *testing.T) {
+	r := NewRouter()
+	r.Get("/typed/:i8/:u/:f/:b/:d/:s", func(w http.ResponseWriter, req *http.Request, i8 int8, u uint, f float64, b bool, d time.Duration, s dummyNamedString) {
+		fmt.Fprintf(w, "%d %d %v %t %v %s", i8, u, f, b, d, s)
+	})
+
+	cases := []struct {
+		inputPath    string
+		expectStatus int
+		expectBody   string
+	}{
+		{"/typed/-1/2/1.5/true/1s/foo", 200, "-1 2 1.5 true 1s foo"},
+		{"/typed/1000/2/1.5/true/1s/foo", 400, "invalid param: i8\n"},
+		{"/typed/1/2/1.5/notbool/1s/foo", 400, "invalid param: b\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestServeHTTPWithParamValidator(t *testing.T) {
+	r := NewRouter()
+	r.Get("/ptr/:id", func(w http.ResponseWriter, req *http.Request, v *dummyParamValidator) {
+		fmt.Fprintf(w, "id=%d", v.id)
+	})
+	r.Get("/value/:id", func(w http.ResponseWriter, req *http.Request, v dummyParamValidator) {
+		fmt.Fprintf(w, "id=%d", v.id)
+	})
+
+	cases := []struct {
+		inputPath    string
+		expectStatus int
+		expectBody   string
+	}{
+		{"/ptr/10", 200, "id=10"},
+		{"/ptr/-1", 400, "invalid param: id: id must be positive number\n"},
+		{"/value/10", 200, "id=10"},
+		{"/value/foo", 400, "invalid param: id: id must be positive number\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestErrorStatusCode(t *testing.T) {
+	cases := []struct {
+		input  error
+		expect int
+	}{
+		{errors.Wrapf(ErrNotFoundHandler, "path=/"), 404},
+		{errors.Wrapf(ErrPathNotFound, "path=/"), 404},
+		{errors.Wrapf(ErrMethodNotAllowed, "method=POST, path=/"), 405},
+		{errors.Wrapf(ErrInvalidParam, "path=/"), 400},
+		{errors.Wrapf(&ParamError{Name: "id", Raw: "foo", Err: errors.New("bad")}, "path=/"), 400},
+		{errors.Wrapf(&BodyError{ContentType: "application/json", Err: errors.New("bad")}, "path=/"), 400},
+		{errors.Wrapf(ErrUnsupportedMediaType, "content-type=text/plain"), 415},
+		{errors.Wrapf(ErrBodyTooLarge, "path=/"), 413},
+		{errors.Wrapf(ErrInvalidHandler, "path=/"), 500},
+		{errors.New("unknown"), 500},
+		{errors.Wrapf(dummyStatusError(http.StatusForbidden), "path=/"), 403},
+	}
+	for i, c := range cases {
+		if result := ErrorStatusCode(c.input); result != c.expect {
+			t.Errorf("#%d: want:%d, got:%d", i, c.expect, result)
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	errReason := errors.New("id must be positive number")
+	var got error
+	r := NewRouter()
+	r.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		got = err
+		w.WriteHeader(http.StatusTeapot)
+	}
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, v *dummyParamValidator) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user/-1", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("want status code:%d, got status code:%d", http.StatusTeapot, w.Code)
+	}
+	if !errors.Is(got, ErrInvalidParam) {
+		t.Errorf("want errors.Is ErrInvalidParam, got %v", got)
+	}
+	var pe *ParamError
+	if !errors.As(got, &pe) {
+		t.Fatalf("want errors.As *ParamError, got %v", got)
+	}
+	if pe.Name != "id" || pe.Raw != "-1" || pe.Err.Error() != errReason.Error() {
+		t.Errorf("want ParamError{id, -1, %v}, got %#v", errReason, pe)
+	}
+}
+
+func TestErrorHandlerRoutingMiss(t *testing.T) {
+	var got error
+	r := NewRouter()
+	r.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		got = err
+		w.WriteHeader(ErrorStatusCode(err))
+	}
+	r.Get("/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+
+	cases := []struct {
+		inputMethod  string
+		inputPath    string
+		expectError  error
+		expectStatus int
+		expectAllow  string
+	}{
+		{"GET", "/none", ErrPathNotFound, 404, ""},
+		{"POST", "/user/10", ErrMethodNotAllowed, 405, "GET, HEAD, OPTIONS"},
+	}
+	for i, c := range cases {
+		got = nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.inputMethod, c.inputPath, nil))
+		if !errors.Is(got, c.expectError) {
+			t.Errorf("#%d: want error:%v, got error:%v", i, c.expectError, got)
+		}
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != c.expectAllow {
+			t.Errorf("#%d: want Allow:%q, got Allow:%q", i, c.expectAllow, allow)
+		}
+	}
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	cases := []struct {
+		input        error
+		expectStatus int
+		expectBody   string
+	}{
+		{errors.Wrapf(ErrNotFoundHandler, "path=/"), 404, "404 page not found\n"},
+		{errors.Wrapf(&ParamError{Name: "id", Raw: "foo", Err: errors.New("bad")}, "path=/"), 400, "invalid param: id\n"},
+		{errors.Wrapf(&ParamError{Name: "id", Raw: "-1", Err: &validationError{err: errors.New("must be positive")}}, "path=/"), 400, "invalid param: id: must be positive\n"},
+		{&ParamError{Name: "q", Err: errRequiredParam}, 400, "invalid param: q: required\n"},
+		{errors.Wrapf(ErrInvalidParam, "path=/"), 400, "Bad Request\n"},
+		{errors.Wrapf(ErrInvalidHandler, "path=/"), 500, "Internal Server Error\n"},
+		{errors.Wrapf(ErrMethodNotAllowed, "path=/"), 405, "405 method not allowed\n"},
+		{errors.Wrapf(&BodyError{ContentType: "application/json", Err: errors.New("unexpected EOF")}, "path=/"), 400, "invalid body\n"},
+		{&BodyError{ContentType: "application/json", Err: &validationError{err: errors.New("name is required")}}, 400, "invalid body: name is required\n"},
+		{&BodyError{ContentType: "application/json", Err: ErrBodyTooLarge}, 413, "Request Entity Too Large\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		NewRouter().handleError(w, httptest.NewRequest("GET", "/", nil), c.input)
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+type dummyPanicParam struct{}
+
+func (p *dummyPanicParam) ValidateParam(raw string) error {
+	panic("panic in param")
+}
+
+func TestRecoverPanic(t *testing.T) {
+	cases := []struct {
+		recoverPanic bool
+		panicHandler func(w http.ResponseWriter, req *http.Request, rcv interface{})
+		inputPath    string
+		expectPanic  bool
+		expectStatus int
+		expectBody   string
+		expectLog    string
+	}{
+		{false, nil, "/handler", true, 0, "", ""},
+		{true, nil, "/handler", false, 500, "Internal Server Error\n", "[panic] panic in handler. method=GET, path=/handler, route=/handler"},
+		{true, nil, "/param/1", false, 500, "Internal Server Error\n", "[panic] panic in param. method=GET, path=/param/1, route=/param/:id"},
+		{
+			true,
+			func(w http.ResponseWriter, req *http.Request, rcv interface{}) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, "%v from %s", rcv, CurrentRoute(req).GetPath())
+			},
+			"/handler",
+			false,
+			503,
+			"panic in handler from /handler",
+			"",
+		},
+	}
+	for i, c := range cases {
+		var buf bytes.Buffer
+		r := NewRouter()
+		r.SetErrLogger(&buf)
+		r.RecoverPanic = c.recoverPanic
+		r.PanicHandler = c.panicHandler
+		r.Get("/handler", func(w http.ResponseWriter, req *http.Request) { panic("panic in handler") })
+		r.Get("/param/:id", func(w http.ResponseWriter, req *http.Request, p *dummyPanicParam) {})
+
+		w := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if rcv := recover(); (rcv != nil) != c.expectPanic {
+					t.Errorf("#%d: want panic:%t, got recovered:%v", i, c.expectPanic, rcv)
+				}
+			}()
+			r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		}()
+		if c.expectPanic {
+			continue
+		}
+
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+		if !strings.Contains(buf.String(), c.expectLog) {
+			t.Errorf("#%d: want log contains:%q, got log:%q", i, c.expectLog, buf.String())
+		}
+		if c.expectLog != "" && !strings.Contains(buf.String(), "goroutine") {
+			t.Errorf("#%d: want log contains stack trace, got log:%q", i, buf.String())
+		}
+	}
+}
+
+type dummyStatusError int
+
+func (e dummyStatusError) Error() string   { return http.StatusText(int(e)) }
+func (e dummyStatusError) StatusCode() int { return int(e) }
+
+func TestServeHTTPWithResults(t *testing.T) {
+	type user struct {
+		ID int `json:"id"`
+	}
+
+	r := NewRouter()
+	r.Get("/error/:id", func(w http.ResponseWriter, req *http.Request, id int) error {
+		if id == 0 {
+			return dummyStatusError(http.StatusNotFound)
+		}
+		if id < 0 {
+			return errors.New("internal")
+		}
+		fmt.Fprintf(w, "id=%d", id)
+		return nil
+	})
+	r.Get("/user/:id", func(req *http.Request, id int) (*user, error) {
+		switch {
+		case id == 0:
+			return nil, nil
+		case id < 0:
+			return nil, dummyStatusError(http.StatusForbidden)
+		}
+		return &user{ID: id}, nil
+	})
+	r.Get("/unsupported", func(req *http.Request) (interface{}, error) {
+		return make(chan int), nil
+	})
+
+	cases := []struct {
+		inputPath    string
+		expectStatus int
+		expectBody   string
+	}{
+		{"/error/1", 200, "id=1"},
+		{"/error/0", 404, "404 page not found\n"},
+		{"/error/-1", 500, "Internal Server Error\n"},
+		{"/user/1", 200, `{"id":1}`},
+		{"/user/0", 204, ""},
+		{"/user/-1", 403, "Forbidden\n"},
+		{"/unsupported", 500, "Internal Server Error\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestServeHTTPWithInjectedArgs(t *testing.T) {
+	type ctxKey struct{}
+
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), ctxKey{}, "ctx")))
+		})
+	})
+	r.Get("/user/:id/:name", func(ctx context.Context, id int, h http.Header, name string, q url.Values, w http.ResponseWriter) {
+		fmt.Fprintf(w, "%v id=%d name=%s header=%s query=%s", ctx.Value(ctxKey{}), id, name, h.Get("X-Foo"), q.Get("q"))
+	})
+	r.Get("/ctx/:id", func(ctx context.Context, id int) (interface{}, error) {
+		return map[string]interface{}{"id": id, "ctx": ctx.Value(ctxKey{})}, nil
+	})
+
+	cases := []struct {
+		inputPath  string
+		expectBody string
+	}{
+		{"/user/10/foo?q=bar", "ctx id=10 name=foo header=baz query=bar"},
+		{"/ctx/10", `{"ctx":"ctx","id":10}`},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.inputPath, nil)
+		req.Header.Set("X-Foo", "baz")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, http.StatusOK, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestPathParamsInContext(t *testing.T) {
+	params := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "id=%s file=%s pattern=%s params=%v", Param(req, "id"), Param(req, "file"), RoutePattern(req), Params(req))
+	}
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Id", Param(req, "id"))
+			next.ServeHTTP(w, req)
+		})
+	})
+	withID := func(w http.ResponseWriter, req *http.Request, id int) { params(w, req) }
+	r.Get("/user/:id", withID)
+	r.Get("/user/:id/files/*file", func(w http.ResponseWriter, req *http.Request, id int, file string) {
+		params(w, req)
+	})
+	r.Get("/static", params)
+	r.Group("/api", func(g *Group) {
+		g.Get("/user/:id", withID)
+	})
+
+	cases := []struct {
+		inputPath    string
+		expectHeader string
+		expectBody   string
+	}{
+		{"/user/10", "10", "id=10 file= pattern=/user/:id params=[{id 10}]"},
+		{"/user/10/files/a/b.txt", "10", "id=10 file=a/b.txt pattern=/user/:id/files/*file params=[{id 10} {file a/b.txt}]"},
+		{"/static", "", "id= file= pattern=/static params=[]"},
+		{"/api/user/20", "20", "id=20 file= pattern=/api/user/:id params=[{id 20}]"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if h := w.Header().Get("X-Id"); h != c.expectHeader {
+			t.Errorf("#%d: want header:%q, got header:%q", i, c.expectHeader, h)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/user/10", nil)
+	if p, ps, pattern := Param(req, "id"), Params(req), RoutePattern(req); p != "" || ps != nil || pattern != "" {
+		t.Errorf("want empty outside the router, got param:%q, params:%v, pattern:%q", p, ps, pattern)
+	}
+}
+
+func TestServeHTTPWithTypedSegment(t *testing.T) {
+	r := NewRouter()
+	r.Get("/items/:id:int", func(w http.ResponseWriter, req *http.Request, id int) {
+		fmt.Fprintf(w, "id=%d", id)
+	})
+	r.Get("/items/:slug:alpha", func(w http.ResponseWriter, req *http.Request, slug string) {
+		fmt.Fprintf(w, "slug=%s", slug)
+	})
+	r.Get("/items/:name", func(w http.ResponseWriter, req *http.Request, name string) {
+		fmt.Fprintf(w, "name=%s pattern=%s", Param(req, "name"), RoutePattern(req))
+	})
+
+	cases := []struct {
+		inputPath    string
+		expectBody   string
+		expectStatus int
+	}{
+		{"/items/10", "id=10", 200},
+		{"/items/foo", "slug=foo", 200},
+		{"/items/foo-10", "name=foo-10 pattern=/items/:name", 200},
+		{"/items/-1", "id=-1", 200},
+		{"/items/+5", "name=+5 pattern=/items/:name", 200},
+		{"/items/99999999999999999999", "name=99999999999999999999 pattern=/items/:name", 200},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.inputPath, nil))
+		if w.Code != c.expectStatus {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectStatus, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package router
 
 import (
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -15,6 +18,20 @@ var (
 )
 
 // Trie is implemente Routing via Trie algorithm
+//
+// the request path is matched segment by segment with the following rules:
+//
+//	static segment "/users/new" is preferred over param segment "/users/:id",
+//	and param segment is preferred over wildcard "/users/*path".
+//	param segment matches a non-empty segment, wildcard matches the rest of path including "/".
+//	when the preferred child does not match the rest of path, the next candidate is tried (backtracking).
+//	e.g. "/users/new/profile" matches "/users/:id/profile" even if "/users/new/edit" is registered.
+//	a trailing "/" and the query string are ignored.
+//
+// param segment can be constrained by the regular expression, ":id<[0-9]+>" or "{name:[a-z]+\.txt}",
+// or the built-in type, ":id:int", ":slug:alpha" or ":uuid:uuid".
+// the constrained params are tried before the unconstrained param in order of registration,
+// and fall through to the next candidate when not matched.
 type Trie struct {
 	root map[string]*Node
 }
@@ -33,6 +50,10 @@ type Data struct {
 	// origin URL path
 	path    string
 	handler baseHandler
+	// precompiled on registration via Router, nil otherwise
+	plan *handlerPlan
+	// constraint of the param node, nil when not constrained
+	constraint func(string) bool
 }
 
 // NewTrie return initialized Trie struct
@@ -44,43 +65,61 @@ func NewTrie() *Trie {
 
 // Lookup returns a HandlerData matching path and method
 func (t *Trie) Lookup(path string, method string) (HandlerData, error) {
-	n, err := t.find(path, method)
+	var ps PathParams
+	n, err := t.match(path, method, &ps)
 	if err != nil {
 		return HandlerData{}, errors.Wrapf(err, "failed lookup. path=%s method=%s", path, method)
 	}
 	return HandlerData{
 		handler: n.data.handler,
-		params:  n.exportParam(path),
+		plan:    n.data.plan,
+		params:  ps.values(),
+		pattern: n.data.path,
 	}, nil
 }
 
+// LookupParams is like Lookup but stores the path parameters to ps without allocation.
+// ps is reset before stored, and reused its capacity. params of the returned HandlerData is nil.
+func (t *Trie) LookupParams(method, path string, ps *PathParams) (HandlerData, bool) {
+	*ps = (*ps)[:0]
+	n, err := t.match(path, method, ps)
+	if err != nil {
+		return HandlerData{}, false
+	}
+	fillParamKeys(n.data.path, *ps)
+	return HandlerData{handler: n.data.handler, pattern: n.data.path, plan: n.data.plan}, true
+}
+
+// AllowedMethods returns the sorted HTTP methods that have a route matching path
+func (t *Trie) AllowedMethods(path string) []string {
+	methods := []string{}
+	for method := range t.root {
+		if _, err := t.find(path, method); err == nil {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 func (t *Trie) find(path string, method string) (*Node, error) {
+	return t.match(path, method, nil)
+}
+
+// match returns the node matched path, and appends the path parameters to ps when not nil
+func (t *Trie) match(path string, method string, ps *PathParams) (*Node, error) {
 	path = trimQueryString(path)
-	parts, err := generateSplitPath(path)
-	if err != nil {
-		return nil, err
+	if len(path) == 0 || string(path[0]) != "/" {
+		return nil, ErrInvalidPathFormat
 	}
 
 	dst, ok := t.root[method]
 	if !ok {
 		return nil, ErrPathNotFound
 	}
-
-	for _, p := range parts {
-		// matched at first
-		if dst.pathEqual(path) {
-			return dst, nil
-		}
-
-		// check childs
-		if n, ok := dst.getChild(p); ok {
-			dst = n
-		}
-		if dst.pathEqual(path) {
-			return dst, nil
-		}
+	if n := dst.match(strings.TrimSuffix(path, "/"), ps); n != nil {
+		return n, nil
 	}
-
 	return nil, ErrPathNotFound
 }
 
@@ -103,6 +142,7 @@ func (t *Trie) Insert(method, path string, handler baseHandler) error {
 			key:     parts[0],
 			path:    path,
 			handler: handler,
+			plan:    routePlan(handler),
 		}
 		return nil
 	}
@@ -110,13 +150,24 @@ func (t *Trie) Insert(method, path string, handler baseHandler) error {
 	// exclude "/"
 	parts = parts[1:]
 	for i, p := range parts {
-		p = convertParamKey(p)
-		if n, ok := dst.getChild(p); ok {
+		var constraint func(string) bool
+		if len(p) != 0 && isParamKey(p) {
+			seg, err := parseParamSegment(p)
+			if err != nil {
+				return errors.Wrapf(err, "failed insert. path=%s, method=%s", path, method)
+			}
+			p, constraint = seg.key(), seg.match
+		}
+		if p == TokenWildcard {
+			return errors.Wrapf(ErrInvalidPathFormat, "empty wildcard name. path=%s, method=%s", path, method)
+		}
+		if n, ok := dst.getChildKey(p); ok {
 			if len(parts)-1 == i {
 				// exist node, but yet registered path and handler
 				if n.data.path == "" {
 					n.data.path = path
 					n.data.handler = handler
+					n.data.plan = routePlan(handler)
 					return nil
 				}
 				return errors.Wrapf(ErrAlreadyPathRegistered, "method=%s, path=%s", method, path)
@@ -125,11 +176,12 @@ func (t *Trie) Insert(method, path string, handler baseHandler) error {
 			continue
 		}
 
-		data := Data{key: p}
+		data := Data{key: p, constraint: constraint}
 		// leaf node
 		if len(parts)-1 == i {
 			data.path = path
 			data.handler = handler
+			data.plan = routePlan(handler)
 		}
 		dst, err = dst.setChild(Node{data: &data})
 		if err != nil {
@@ -140,11 +192,10 @@ func (t *Trie) Insert(method, path string, handler baseHandler) error {
 }
 
 func trimQueryString(s string) string {
-	if len(s) == 0 {
-		return ""
+	if i := strings.Index(s, TokenQueryString); i >= 0 {
+		return s[:i]
 	}
-	ss := strings.Split(s, TokenQueryString)
-	return ss[0]
+	return s
 }
 
 func generateSplitPath(s string) ([]string, error) {
@@ -170,46 +221,413 @@ func validatePath(s string) (string, error) {
 	return s, nil
 }
 
-func convertParamKey(s string) string {
-	if isParamKey(s) {
-		return TokenParam
+// isParamKey reports whether s is the param segment, ":name" or "{name}"
+func isParamKey(s string) bool {
+	return string(s[0]) == TokenParam || s[0] == '{'
+}
+
+// paramSegment is the parsed param segment of the pattern
+type paramSegment struct {
+	name string
+	// source of the regexp constraint, empty when not constrained
+	expr string
+	// name of the built-in type, empty when not typed
+	typ string
+	// reports whether the segment satisfies the constraint, nil when not constrained
+	match func(string) bool
+}
+
+// segmentType is the built-in type of the param segment, e.g. ":id:int"
+type segmentType struct {
+	// reports whether the segment has the shape of the type
+	match func(string) bool
+	// kinds of the handler arg converted from the segment, hold any segment matched the shape
+	kinds []reflect.Kind
+}
+
+// segmentTypes are the built-in types of the param segment
+var segmentTypes = map[string]segmentType{
+	"int":   {isIntSegment, []reflect.Kind{reflect.Int, reflect.Int64, reflect.Float64, reflect.String}},
+	"alpha": {isAlphaSegment, []reflect.Kind{reflect.String}},
+	"uuid":  {isUUIDSegment, []reflect.Kind{reflect.String}},
+}
+
+// parseParamSegment parses the param segment, and compiles the constraint.
+//
+//	":name", "{name}": matches any non-empty segment
+//	":name<regexp>", "{name:regexp}": matches the segment entirely matched the regexp
+//	":name:type", "{name:type}": matches the segment has the shape of the built-in type, see segmentTypes
+//
+// the names of the built-in types in the braces are the types, not the regexp. e.g. "{id:int}" is same as ":id:int".
+func parseParamSegment(s string) (paramSegment, error) {
+	var seg paramSegment
+	// whether the constraint or the type is declared
+	constrained := false
+	if s[0] == '{' {
+		if len(s) < 2 || s[len(s)-1] != '}' {
+			return seg, errors.Wrapf(ErrInvalidPathFormat, "unclosed param. segment=%s", s)
+		}
+		seg.name = s[1 : len(s)-1]
+		if i := strings.Index(seg.name, ":"); i >= 0 {
+			seg.name, seg.expr = seg.name[:i], seg.name[i+1:]
+			constrained = true
+		}
+		if _, ok := segmentTypes[seg.expr]; ok {
+			seg.typ, seg.expr = seg.expr, ""
+		}
+	} else {
+		seg.name = s[1:]
+		if i := strings.IndexAny(seg.name, "<:"); i >= 0 {
+			rest := seg.name[i:]
+			seg.name = seg.name[:i]
+			constrained = true
+			if rest[0] == ':' {
+				seg.typ = rest[1:]
+			} else if len(rest) < 2 || rest[len(rest)-1] != '>' {
+				return seg, errors.Wrapf(ErrInvalidPathFormat, "unclosed constraint. segment=%s", s)
+			} else {
+				seg.expr = rest[1 : len(rest)-1]
+			}
+		}
 	}
-	return s
+	if len(seg.name) == 0 {
+		return seg, errors.Wrapf(ErrInvalidPathFormat, "empty param name. segment=%s", s)
+	}
+	if constrained && len(seg.typ) == 0 && len(seg.expr) == 0 {
+		return seg, errors.Wrapf(ErrInvalidPathFormat, "empty constraint. segment=%s", s)
+	}
+
+	switch {
+	case len(seg.typ) != 0:
+		st, ok := segmentTypes[seg.typ]
+		if !ok {
+			return seg, errors.Wrapf(ErrInvalidPathFormat, "unknown param type. segment=%s", s)
+		}
+		seg.match = st.match
+	case len(seg.expr) != 0:
+		re, err := regexp.Compile("^(?:" + seg.expr + ")$")
+		if err != nil {
+			return seg, errors.Wrapf(ErrInvalidPathFormat, "invalid constraint. segment=%s, error=%v", s, err)
+		}
+		seg.match = re.MatchString
+	}
+	return seg, nil
 }
 
-func isParamKey(s string) bool {
-	return string(s[0]) == TokenParam
+// key returns the node key, the params with the same constraint share the node regardless of the name
+func (seg paramSegment) key() string {
+	switch {
+	case len(seg.typ) != 0:
+		return TokenParam + TokenParam + seg.typ
+	case len(seg.expr) != 0:
+		return TokenParam + "<" + seg.expr + ">"
+	}
+	return TokenParam
+}
+
+// segmentName returns the name of the param or wildcard segment without the constraint
+func segmentName(s string) string {
+	if s[0] == '{' {
+		s = strings.TrimSuffix(s[1:], "}")
+		if i := strings.Index(s, ":"); i >= 0 {
+			return s[:i]
+		}
+		return s
+	}
+	if string(s[0]) == TokenParam {
+		if i := strings.IndexAny(s[1:], "<:"); i >= 0 {
+			return s[1 : i+1]
+		}
+	}
+	return s[1:]
+}
+
+// paramSegmentTypes returns the built-in types of ":param" and "*wildcard" in the path, empty when not typed.
+// e.g. "/user/:id:int/:name" => ["int", ""]
+func paramSegmentTypes(path string) ([]string, error) {
+	parts, err := generateSplitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	types := []string{}
+	for _, p := range parts[1:] {
+		switch {
+		case len(p) == 0:
+		case isParamKey(p):
+			seg, err := parseParamSegment(p)
+			if err != nil {
+				return nil, err
+			}
+			types = append(types, seg.typ)
+		case isWildcardKey(p):
+			types = append(types, "")
+		}
+	}
+	return types, nil
+}
+
+// validateSegmentType returns error when the handler arg t is not converted from the built-in type typ.
+// the typed params are converted by the declared type via segmentConverter, thereby t is must be the kinds of the type,
+// and must not implement the validations or encoding.TextUnmarshaler, nor have the registered Converter, converted otherwise.
+func validateSegmentType(typ string, t reflect.Type, registered registeredLookup) error {
+	st, ok := segmentTypes[typ]
+	if !ok {
+		return nil
+	}
+	if registered != nil && registered(t) {
+		return errors.Wrapf(ErrInvalidHandler, "param type is converted by the declared %s, must not have the registered Converter. got:%v", typ, t)
+	}
+	if t == durationType ||
+		reflect.PtrTo(t).Implements(paramValidatorType) ||
+		reflect.PtrTo(t).Implements(validationParamType) ||
+		reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return errors.Wrapf(ErrInvalidHandler, "param type is converted by the declared %s, must not have the custom conversion. got:%v", typ, t)
+	}
+	for _, k := range st.kinds {
+		if t.Kind() == k {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrInvalidHandler, "param type mismatch. declared %s, got:%v", typ, t)
+}
+
+// maxInt64Digits is the decimal digits of the max int64
+const maxInt64Digits = "9223372036854775807"
+
+// isIntSegment reports whether s is the decimal integer fits in int64, optionally signed by "-"
+func isIntSegment(s string) bool {
+	neg := len(s) > 0 && s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	// compare with the max int64 by the digits without the leading zeros
+	s = strings.TrimLeft(s, "0")
+	if len(s) != len(maxInt64Digits) {
+		return len(s) < len(maxInt64Digits)
+	}
+	if neg {
+		// the min int64 is -9223372036854775808
+		return s <= "9223372036854775808"
+	}
+	return s <= maxInt64Digits
+}
+
+// isAlphaSegment reports whether s consists of the ASCII letters
+func isAlphaSegment(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUIDSegment reports whether s is the UUID in the canonical form, e.g. "123e4567-e89b-12d3-a456-426614174000"
+func isUUIDSegment(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+			continue
+		}
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
 }
 
 func isWildcardKey(s string) bool {
 	return string(s[0]) == TokenWildcard
 }
 
-func (n *Node) getChild(key string) (*Node, bool) {
-	if n.child == nil {
-		return nil, false
+// getChildKey returns the child registered by the key.
+// wildcard keys are matched regardless of the name, e.g. "*filepath" and "*path".
+func (n *Node) getChildKey(key string) (*Node, bool) {
+	if len(key) != 0 && isWildcardKey(key) {
+		return n.getChildWild()
 	}
+	for child := n.child; child != nil; child = child.bros {
+		if child.data.key == key {
+			return child, true
+		}
+	}
+	return nil, false
+}
 
-	// search match key node
-	child := n.child
-	if child.data.key == key {
-		return child, true
+// match returns the node matched the rest of path, path is empty at the end or begins with "/".
+// children are tried in order of static, param and wildcard, and backtracks when the descendants do not match.
+func (n *Node) match(path string, ps *PathParams) *Node {
+	if len(path) == 0 {
+		if len(n.data.path) != 0 {
+			return n
+		}
+		// wildcard matches the empty rest of path
+		if wild, ok := n.getChildWild(); ok && len(wild.data.path) != 0 {
+			appendParam(ps, "")
+			return wild
+		}
+		return nil
 	}
-	if bros, ok := child.getBros(key); ok {
-		return bros, true
+
+	seg, rest := path[1:], ""
+	if i := strings.Index(seg, "/"); i >= 0 {
+		seg, rest = seg[:i], seg[i:]
 	}
 
-	// search param node
-	if childParam, ok := n.getChildParam(); ok {
-		return childParam, true
+	for child := n.child; child != nil; child = child.bros {
+		key := child.data.key
+		if len(key) == 0 || isParamKey(key) || isWildcardKey(key) || key != seg {
+			continue
+		}
+		if m := child.match(rest, ps); m != nil {
+			return m
+		}
 	}
 
-	// search wildcard node
-	if childWild, ok := n.getChildWild(); ok {
-		return childWild, true
+	if len(seg) != 0 {
+		// the constrained params are tried before the unconstrained param
+		for child := n.child; child != nil; child = child.bros {
+			if child.data.constraint == nil || !child.data.constraint(seg) {
+				continue
+			}
+			if m := child.matchParam(seg, rest, ps); m != nil {
+				return m
+			}
+		}
+		if param, ok := n.getChildParam(); ok {
+			if m := param.matchParam(seg, rest, ps); m != nil {
+				return m
+			}
+		}
 	}
 
-	return nil, false
+	// wildcard does not begin with the empty segment, e.g. "/users//profile" does not match "/users/*path"
+	if wild, ok := n.getChildWild(); ok && len(wild.data.path) != 0 && (len(seg) != 0 || len(rest) == 0) {
+		appendParam(ps, path[1:])
+		return wild
+	}
+	return nil
+}
+
+// matchParam captures seg as the param of n, and matches the rest of path.
+// the captured param is dropped when the rest does not match.
+func (n *Node) matchParam(seg, rest string, ps *PathParams) *Node {
+	size := paramsLen(ps)
+	appendParam(ps, seg)
+	if m := n.match(rest, ps); m != nil {
+		return m
+	}
+	truncateParams(ps, size)
+	return nil
+}
+
+// paramsCap is the initial capacity of the path parameters, enough for the most of routes
+const paramsCap = 4
+
+// appendParam appends the value of the path parameter, the key is filled after matched via fillParamKeys
+func appendParam(ps *PathParams, v string) {
+	if ps == nil {
+		return
+	}
+	if cap(*ps) == 0 {
+		*ps = make(PathParams, 0, paramsCap)
+	}
+	*ps = append(*ps, PathParam{Value: v})
+}
+
+func paramsLen(ps *PathParams) int {
+	if ps == nil {
+		return 0
+	}
+	return len(*ps)
+}
+
+func truncateParams(ps *PathParams, size int) {
+	if ps != nil {
+		*ps = (*ps)[:size]
+	}
+}
+
+// fillParamKeys sets the names of ":param" and "*wildcard" in the pattern to the keys of ps in order
+func fillParamKeys(pattern string, ps PathParams) {
+	i := 0
+	for len(pattern) > 0 && i < len(ps) {
+		seg := pattern[1:]
+		pattern = ""
+		if end := strings.Index(seg, "/"); end >= 0 {
+			seg, pattern = seg[:end], seg[end:]
+		}
+		if len(seg) != 0 && (isParamKey(seg) || isWildcardKey(seg)) {
+			ps[i].Key = segmentName(seg)
+			i++
+		}
+	}
+}
+
+// appendParams appends the path parameters of hd to ps in order of the pattern.
+// the params are taken from hd.params when looked up with the values,
+// otherwise extracted by walking the pattern and the looked up path in lockstep.
+func (hd HandlerData) appendParams(ps *PathParams) {
+	start := len(*ps)
+	if hd.params != nil {
+		for _, v := range hd.params {
+			raw, _ := v.(string)
+			*ps = append(*ps, PathParam{Value: raw})
+		}
+		fillParamKeys(hd.pattern, (*ps)[start:])
+		return
+	}
+
+	pattern, path := hd.pattern, hd.path
+	for len(pattern) > 0 {
+		key := pattern[1:]
+		pattern = ""
+		if end := strings.Index(key, "/"); end >= 0 {
+			key, pattern = key[:end], key[end:]
+		}
+		isParam := len(key) != 0 && isParamKey(key)
+		isWild := len(key) != 0 && isWildcardKey(key)
+
+		seg, rest := "", ""
+		if len(path) > 0 {
+			seg, rest = path[1:], ""
+			if end := strings.Index(seg, "/"); end >= 0 {
+				seg, rest = seg[:end], seg[end:]
+			}
+		}
+		switch {
+		case isWild:
+			// wildcard captures the rest of path, empty when path is ended
+			v := ""
+			if len(path) > 0 {
+				v = path[1:]
+			}
+			*ps = append(*ps, PathParam{Key: segmentName(key), Value: v})
+			return
+		case isParam:
+			*ps = append(*ps, PathParam{Key: segmentName(key), Value: seg})
+		}
+		path = rest
+	}
 }
 
 func (n *Node) getChildParam() (*Node, bool) {
@@ -218,7 +636,7 @@ func (n *Node) getChildParam() (*Node, bool) {
 	}
 
 	child := n.child
-	if isParamKey(child.data.key) {
+	if child.data.key == TokenParam {
 		return child, true
 	}
 	if bros, ok := child.getBrosParam(); ok {
@@ -242,25 +660,13 @@ func (n *Node) getChildWild() (*Node, bool) {
 	return nil, false
 }
 
-func (n *Node) getBros(key string) (*Node, bool) {
-	if n.bros == nil {
-		return nil, false
-	}
-
-	bros := n.bros
-	if bros.data.key == key {
-		return bros, true
-	}
-	return bros.getBros(key)
-}
-
 func (n *Node) getBrosParam() (*Node, bool) {
 	if n.bros == nil {
 		return nil, false
 	}
 
 	bros := n.bros
-	if isParamKey(bros.data.key) {
+	if bros.data.key == TokenParam {
 		return bros, true
 	}
 	return bros.getBrosParam()
@@ -279,7 +685,7 @@ func (n *Node) getBrosWild() (*Node, bool) {
 }
 
 func (n *Node) setChild(node Node) (*Node, error) {
-	if _, ok := n.getChild(node.data.key); ok {
+	if _, ok := n.getChildKey(node.data.key); ok {
 		return nil, ErrAlreadyPathRegistered
 	}
 
@@ -301,64 +707,3 @@ func (n *Node) getLastBros() *Node {
 	}
 	return n.bros.getLastBros()
 }
-
-func (n *Node) pathEqual(path string) bool {
-	if len(n.data.path) == 0 {
-		return false
-	}
-	a, err := generateSplitPath(n.data.path)
-	if err != nil {
-		return false
-	}
-	b, err := generateSplitPath(path)
-	if err != nil {
-		return false
-	}
-	if len(a) != len(b) && !strings.Contains(n.data.path, TokenWildcard) {
-		return false
-	}
-	for i, v := range a {
-		// wildcard matches all paths
-		if len(v) != 0 && isWildcardKey(v) {
-			return true
-		}
-
-		// param symbol is match any
-		if len(v) != 0 && isParamKey(v) {
-			continue
-		}
-		if v != string(b[i]) {
-			return false
-		}
-	}
-	return true
-}
-
-func (n *Node) exportParam(path string) []interface{} {
-	p := []interface{}{}
-	if len(n.data.path) == 0 {
-		return p
-	}
-	a, err := generateSplitPath(n.data.path)
-	if err != nil {
-		return p
-	}
-	b, err := generateSplitPath(path)
-	if err != nil {
-		return p
-	}
-	if len(a) != len(b) && !strings.Contains(n.data.path, TokenWildcard) {
-		return p
-	}
-
-	for i, v := range a {
-		if len(v) != 0 && isParamKey(v) {
-			p = append(p, b[i])
-		}
-		// convert the path following TokenWildcard to parameters
-		if len(v) != 0 && isWildcardKey(v) {
-			p = append(p, strings.Join(b[i:], "/"))
-		}
-	}
-	return p
-}
@@ -112,49 +112,6 @@ func generateFixture() (Trie, map[string]*Node) {
 	return trie, nodes
 }
 
-func TestGetBros(t *testing.T) {
-	setupFixture()
-
-	cases := []struct {
-		start      *Node
-		input      string
-		expectNode *Node
-		expectBool bool
-	}{
-		{helperNodes["user2a"], ":", helperNodes["user2b"], true},
-		{helperNodes["shop3a"], ":", helperNodes["shop3b"], true},
-		{helperNodes["shop2a"], "detail", nil, false},
-	}
-	for i, c := range cases {
-		result, ok := c.start.getBros(c.input)
-		if !reflect.DeepEqual(result, c.expectNode) || c.expectBool != ok {
-			t.Errorf("#%d: want result:%#v ok:%t, got result:%#v ok:%t", i, c.expectNode, c.expectBool, result, ok)
-		}
-	}
-}
-
-func TestGetChild(t *testing.T) {
-	setupFixture()
-
-	cases := []struct {
-		start      *Node
-		input      string
-		expectNode *Node
-		expectBool bool
-	}{
-		{helperNodes["root"], "user", helperNodes["user1a"], true},
-		{helperNodes["root"], "none", nil, false},
-		{helperNodes["root"], ":", nil, false},
-		{helperNodes["user1a"], "10", helperNodes["user2b"], true},
-	}
-	for i, c := range cases {
-		result, ok := c.start.getChild(c.input)
-		if !reflect.DeepEqual(result, c.expectNode) || c.expectBool != ok {
-			t.Errorf("#%d: want result:%#v ok:%t, got result:%#v ok:%t", i, c.expectNode, c.expectBool, result, ok)
-		}
-	}
-}
-
 func TestGetBrosParam(t *testing.T) {
 	setupFixture()
 
@@ -252,56 +209,6 @@ func TestSetChild(t *testing.T) {
 	}
 }
 
-func TestPathEqual(t *testing.T) {
-	cases := []struct {
-		baseNode *Node
-		input    string
-		expect   bool
-	}{
-		{
-			&Node{data: &Data{path: "/user/:userID/follow"}},
-			"/user/10/follow/",
-			true,
-		},
-		{
-			&Node{data: &Data{path: "/"}},
-			"/",
-			true,
-		},
-		{
-			&Node{data: &Data{path: "/user/:userID/follow"}},
-			"user/10/follow/",
-			false,
-		},
-		{
-			&Node{data: &Data{path: "/user/:userID/follow"}},
-			"/user/10/follow/10",
-			false,
-		},
-		{
-			&Node{data: &Data{path: "/user/:userID/follow"}},
-			"/user/10/dummy/",
-			false,
-		},
-		{
-			&Node{data: &Data{path: "/static/css/*filepath"}},
-			"/static/css/foo",
-			true,
-		},
-		{
-			&Node{data: &Data{path: "/static/css/*filepath"}},
-			"/static/css/foo/bar",
-			true,
-		},
-	}
-	for i, c := range cases {
-		result := c.baseNode.pathEqual(c.input)
-		if result != c.expect {
-			t.Errorf("#%d: want:%#v , got:%#v ", i, c.expect, result)
-		}
-	}
-}
-
 func TestFind(t *testing.T) {
 	setupFixture()
 
@@ -377,51 +284,6 @@ func TestInsert(t *testing.T) {
 	}
 }
 
-func TestExportParam(t *testing.T) {
-	cases := []struct {
-		baseNode *Node
-		input    string
-		expect   []interface{}
-	}{
-		{
-			&Node{data: &Data{path: "/user/:userID/follow/:attrID"}},
-			"/user/10/follow/20/",
-			[]interface{}{"10", "20"},
-		},
-		{
-			&Node{data: &Data{path: "/user/list"}},
-			"/user/list",
-			[]interface{}{},
-		},
-		{
-			&Node{data: &Data{path: "/user/:userID/follow"}},
-			"/user/10/follow/10",
-			[]interface{}{},
-		},
-		{
-			&Node{data: &Data{path: "/user/:userID/follow"}},
-			"user/10/follow/10",
-			[]interface{}{},
-		},
-		{
-			&Node{data: &Data{}},
-			"/user/10/follow/10",
-			[]interface{}{},
-		},
-		{
-			&Node{data: &Data{path: "/static/css/*filepath"}},
-			"/static/css/foo/bar",
-			[]interface{}{"foo/bar"},
-		},
-	}
-	for i, c := range cases {
-		result := c.baseNode.exportParam(c.input)
-		if !reflect.DeepEqual(result, c.expect) {
-			t.Errorf("#%d: want:%#v , got:%#v ", i, c.expect, result)
-		}
-	}
-}
-
 func TestLookup(t *testing.T) {
 	setupFixture()
 
@@ -434,7 +296,7 @@ func TestLookup(t *testing.T) {
 		{
 			"/shop/10/20/",
 			"GET",
-			HandlerData{handler: nil, params: []interface{}{"10", "20"}},
+			HandlerData{handler: nil, params: []interface{}{"10", "20"}, pattern: "/shop/:shopID/:paymentID"},
 			nil,
 		},
 		{
@@ -454,3 +316,245 @@ func TestLookup(t *testing.T) {
 		}
 	}
 }
+
+func TestAllowedMethods(t *testing.T) {
+	setupFixture()
+	fixtureTrie.Insert("POST", "/user/:userID", nil)
+	fixtureTrie.Insert("DELETE", "/user/list", nil)
+
+	cases := []struct {
+		input  string
+		expect []string
+	}{
+		{"/user/10", []string{"GET", "POST"}},
+		{"/user/list", []string{"DELETE", "GET", "POST"}},
+		{"/static/css/foo", []string{"GET"}},
+		{"/none", []string{}},
+		{"none", []string{}},
+	}
+	for i, c := range cases {
+		result := fixtureTrie.AllowedMethods(c.input)
+		if !reflect.DeepEqual(result, c.expect) {
+			t.Errorf("#%d: want:%#v , got:%#v ", i, c.expect, result)
+		}
+	}
+}
+
+// nilIfEmpty returns nil for the empty s, to compare the nil and the empty slices by reflect.DeepEqual
+func nilIfEmpty[T any](s []T) []T {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+// paramValues returns the values of the path parameters of hd
+func paramValues(hd HandlerData) []interface{} {
+	var ps PathParams
+	hd.appendParams(&ps)
+	return ps.values()
+}
+
+func TestLookupPriority(t *testing.T) {
+	paths := []string{
+		"/users/:id/profile",
+		"/users/new/edit",
+		"/users/new",
+		"/users/:id",
+		"/users/*path",
+		"/files/*filepath",
+		"/files/static/:name",
+		"/a/:b/c",
+		"/a/b/:c/d",
+		"/",
+	}
+	cases := []struct {
+		input        string
+		expectPath   string
+		expectParams []interface{}
+		expectError  error
+	}{
+		{"/", "/", []interface{}{}, nil},
+		{"/users/new", "/users/new", []interface{}{}, nil},
+		{"/users/new/", "/users/new", []interface{}{}, nil},
+		{"/users/new/edit", "/users/new/edit", []interface{}{}, nil},
+		{"/users/new/profile", "/users/:id/profile", []interface{}{"new"}, nil},
+		{"/users/10", "/users/:id", []interface{}{"10"}, nil},
+		{"/users/10/profile?q=1", "/users/:id/profile", []interface{}{"10"}, nil},
+		{"/users/10/edit", "/users/*path", []interface{}{"10/edit"}, nil},
+		{"/users/new/edit/more", "/users/*path", []interface{}{"new/edit/more"}, nil},
+		{"/files/static/foo", "/files/static/:name", []interface{}{"foo"}, nil},
+		{"/files/static/foo/bar", "/files/*filepath", []interface{}{"static/foo/bar"}, nil},
+		{"/files/static", "/files/*filepath", []interface{}{"static"}, nil},
+		{"/files", "/files/*filepath", []interface{}{""}, nil},
+		{"/a/b/c", "/a/:b/c", []interface{}{"b"}, nil},
+		{"/a/b/x/d", "/a/b/:c/d", []interface{}{"x"}, nil},
+		{"/a/b/c/d", "/a/b/:c/d", []interface{}{"c"}, nil},
+		{"/a/x/d", "", nil, ErrPathNotFound},
+		{"/users//profile", "", nil, ErrPathNotFound},
+		{"/files//foo", "", nil, ErrPathNotFound},
+		{"/a//c", "", nil, ErrPathNotFound},
+		{"//", "", nil, ErrPathNotFound},
+		{"/none", "", nil, ErrPathNotFound},
+		{"none", "", nil, ErrInvalidPathFormat},
+		{"", "", nil, ErrInvalidPathFormat},
+	}
+	for name, routing := range map[string]Routing{"Trie": NewTrie(), "RadixTree": NewRadixTree()} {
+		for _, p := range paths {
+			if err := routing.Insert("GET", p, p); err != nil {
+				t.Fatalf("%s: want no error, got %v. path=%s", name, err, p)
+			}
+		}
+		for i, c := range cases {
+			result, err := routing.Lookup(c.input, "GET")
+			if errors.Cause(err) != c.expectError {
+				t.Errorf("%s #%d: want error:%v, got error:%v", name, i, c.expectError, err)
+			}
+			if err != nil {
+				continue
+			}
+			if result.pattern != c.expectPath || result.handler != c.expectPath {
+				t.Errorf("%s #%d: want path:%s, got path:%s", name, i, c.expectPath, result.pattern)
+			}
+			if got := paramValues(result); !reflect.DeepEqual(nilIfEmpty(got), nilIfEmpty(c.expectParams)) {
+				t.Errorf("%s #%d: want params:%#v, got params:%#v", name, i, c.expectParams, got)
+			}
+		}
+	}
+}
+
+// lookupCase is the expected result of LookupParams, not found when expectPath is empty
+type lookupCase struct {
+	input        string
+	expectPath   string
+	expectParams PathParams
+}
+
+// testLookupTable registers paths to both Trie and RadixTree, and checks LookupParams by cases
+func testLookupTable(t *testing.T, paths []string, cases []lookupCase) {
+	t.Helper()
+	for name, routing := range map[string]paramsRouting{"Trie": NewTrie(), "RadixTree": NewRadixTree()} {
+		for _, p := range paths {
+			if err := routing.(Routing).Insert("GET", p, p); err != nil {
+				t.Fatalf("%s: want no error, got %v. path=%s", name, err, p)
+			}
+		}
+		for i, c := range cases {
+			var ps PathParams
+			result, ok := routing.LookupParams("GET", c.input, &ps)
+			if want := len(c.expectPath) != 0; ok != want {
+				t.Errorf("%s #%d: want found:%t, got found:%t. path=%s", name, i, want, ok, c.input)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if result.pattern != c.expectPath {
+				t.Errorf("%s #%d: want path:%s, got path:%s", name, i, c.expectPath, result.pattern)
+			}
+			if !reflect.DeepEqual(nilIfEmpty(ps), nilIfEmpty(c.expectParams)) {
+				t.Errorf("%s #%d: want params:%v, got params:%v", name, i, c.expectParams, ps)
+			}
+		}
+	}
+}
+
+func TestLookupConstraint(t *testing.T) {
+	paths := []string{
+		"/users/:id<[0-9]+>",
+		"/users/:name",
+		"/users/new",
+		"/users/{id:[0-9]+}/posts/{slug:[a-z-]+}",
+		"/users/:id<[0-9]+>/:tab",
+		"/files/{name:[a-z]+\\.txt}",
+		"/files/{name:[a-z]+\\.md}",
+		"/files/*filepath",
+	}
+	testLookupTable(t, paths, []lookupCase{
+		{"/users/10", "/users/:id<[0-9]+>", PathParams{{"id", "10"}}},
+		{"/users/bob", "/users/:name", PathParams{{"name", "bob"}}},
+		{"/users/10x", "/users/:name", PathParams{{"name", "10x"}}},
+		{"/users/new", "/users/new", nil},
+		{"/users/10/posts/hello-world", "/users/{id:[0-9]+}/posts/{slug:[a-z-]+}", PathParams{{"id", "10"}, {"slug", "hello-world"}}},
+		{"/users/10/posts/Hello", "", nil},
+		{"/users/10/likes", "/users/:id<[0-9]+>/:tab", PathParams{{"id", "10"}, {"tab", "likes"}}},
+		{"/users/bob/likes", "", nil},
+		{"/files/readme.txt", "/files/{name:[a-z]+\\.txt}", PathParams{{"name", "readme.txt"}}},
+		{"/files/readme.md", "/files/{name:[a-z]+\\.md}", PathParams{{"name", "readme.md"}}},
+		{"/files/readme.go", "/files/*filepath", PathParams{{"filepath", "readme.go"}}},
+		{"/files/readmextxt", "/files/*filepath", PathParams{{"filepath", "readmextxt"}}},
+	})
+}
+
+func TestLookupTypedSegment(t *testing.T) {
+	paths := []string{
+		"/items/:id:int",
+		"/items/:slug:alpha",
+		"/items/:uuid:uuid",
+		"/items/:name",
+		"/items/:id:int/:tab",
+		"/codes/{code:int}",
+	}
+	testLookupTable(t, paths, []lookupCase{
+		{"/items/10", "/items/:id:int", PathParams{{"id", "10"}}},
+		{"/items/-10", "/items/:id:int", PathParams{{"id", "-10"}}},
+		{"/items/Foo", "/items/:slug:alpha", PathParams{{"slug", "Foo"}}},
+		{"/items/123e4567-e89b-12d3-a456-426614174000", "/items/:uuid:uuid", PathParams{{"uuid", "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/items/123e4567-e89b-12d3-a456-42661417400z", "/items/:name", PathParams{{"name", "123e4567-e89b-12d3-a456-42661417400z"}}},
+		{"/items/10x", "/items/:name", PathParams{{"name", "10x"}}},
+		{"/items/-", "/items/:name", PathParams{{"name", "-"}}},
+		{"/items/10/detail", "/items/:id:int/:tab", PathParams{{"id", "10"}, {"tab", "detail"}}},
+		{"/items/Foo/detail", "", nil},
+		{"/items/+5", "/items/:name", PathParams{{"name", "+5"}}},
+		{"/items/9223372036854775807", "/items/:id:int", PathParams{{"id", "9223372036854775807"}}},
+		{"/items/9223372036854775808", "/items/:name", PathParams{{"name", "9223372036854775808"}}},
+		{"/items/-9223372036854775808", "/items/:id:int", PathParams{{"id", "-9223372036854775808"}}},
+		{"/items/-9223372036854775809", "/items/:name", PathParams{{"name", "-9223372036854775809"}}},
+		{"/items/99999999999999999999", "/items/:name", PathParams{{"name", "99999999999999999999"}}},
+		{"/items/000000000000000000001", "/items/:id:int", PathParams{{"id", "000000000000000000001"}}},
+		{"/codes/10", "/codes/{code:int}", PathParams{{"code", "10"}}},
+		{"/codes/int", "", nil},
+	})
+}
+
+func TestInsertConflict(t *testing.T) {
+	cases := []struct {
+		input     []string
+		expectErr error
+	}{
+		{[]string{"/users/:id", "/users/new"}, nil},
+		{[]string{"/users/new", "/users/:id"}, nil},
+		{[]string{"/users/*path", "/users/new", "/users/:id"}, nil},
+		{[]string{"/users/:id", "/users/:name"}, ErrAlreadyPathRegistered},
+		{[]string{"/users/:id/a", "/users/:name/b"}, nil},
+		{[]string{"/files/*filepath", "/files/*path"}, ErrAlreadyPathRegistered},
+		{[]string{"/files/*filepath", "/files/*filepath/foo"}, ErrAlreadyWildcardPathRegistered},
+		{[]string{"/users/:id<[0-9]+>", "/users/:name"}, nil},
+		{[]string{"/users/:id<[0-9]+>", "/users/{num:[0-9]+}"}, ErrAlreadyPathRegistered},
+		{[]string{"/users/:id<[0-9]+>", "/users/:id<[a-z]+>"}, nil},
+		{[]string{"/users/:id<[0-9]+"}, ErrInvalidPathFormat},
+		{[]string{"/users/:id<[0-9+>"}, ErrInvalidPathFormat},
+		{[]string{"/users/{id"}, ErrInvalidPathFormat},
+		{[]string{"/users/{:[0-9]+}"}, ErrInvalidPathFormat},
+		{[]string{"/items/:id:int", "/items/:name"}, nil},
+		{[]string{"/items/:id:int", "/items/:slug:alpha", "/items/:uuid:uuid"}, nil},
+		{[]string{"/items/:id:int", "/items/:num:int"}, ErrAlreadyPathRegistered},
+		{[]string{"/items/:id:float"}, ErrInvalidPathFormat},
+		{[]string{"/items/:id:"}, ErrInvalidPathFormat},
+		{[]string{"/files/*"}, ErrInvalidPathFormat},
+		{[]string{"/*"}, ErrInvalidPathFormat},
+	}
+	for i, c := range cases {
+		for name, routing := range map[string]Routing{"Trie": NewTrie(), "RadixTree": NewRadixTree()} {
+			var err error
+			for _, p := range c.input {
+				if err = routing.Insert("GET", p, nil); err != nil {
+					break
+				}
+			}
+			if errors.Cause(err) != c.expectErr {
+				t.Errorf("%s #%d: want error:%v, got error:%v", name, i, c.expectErr, err)
+			}
+		}
+	}
+}
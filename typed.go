@@ -0,0 +1,180 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// HandlerFunc is the handler receiving the path parameters by name.
+// called without reflection, e.g. r.Get("/user/:id", router.HandlerFunc(getUser))
+type HandlerFunc func(w http.ResponseWriter, req *http.Request, ps PathParams)
+
+// Handle1 register the handler receiving a path parameter, the type is checked by the compiler.
+// e.g. router.Handle1(r, "GET", "/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+func Handle1[A any](r *Router, method, path string, h func(http.ResponseWriter, *http.Request, A)) (*Route, error) {
+	names, types, err := typedParams(path, 1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+	decodeA, err := typedParamDecoder[A](names[0], types[0], r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+
+	plan := &handlerPlan{fn: reflect.ValueOf(h)}
+	plan.typed = func(w http.ResponseWriter, req *http.Request, ps PathParams) error {
+		if len(ps) != 1 {
+			return errors.Wrapf(ErrNotFoundHandler, "path=%s, handler=%T", req.URL.Path, h)
+		}
+		a, err := decodeA(ps[0].Value)
+		if err != nil {
+			return errors.Wrapf(err, "path=%s", req.URL.Path)
+		}
+		h(w, req, a)
+		return nil
+	}
+	return r.handlePlan(method, path, h, plan)
+}
+
+// Handle2 register the handler receiving two path parameters in order, the types are checked by the compiler.
+// e.g. router.Handle2(r, "GET", "/user/:id/:name", func(w http.ResponseWriter, req *http.Request, id int, name string) {})
+func Handle2[A, B any](r *Router, method, path string, h func(http.ResponseWriter, *http.Request, A, B)) (*Route, error) {
+	names, types, err := typedParams(path, 2)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+	decodeA, err := typedParamDecoder[A](names[0], types[0], r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+	decodeB, err := typedParamDecoder[B](names[1], types[1], r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed registered path. method=%s, path=%s", method, path)
+	}
+
+	plan := &handlerPlan{fn: reflect.ValueOf(h)}
+	plan.typed = func(w http.ResponseWriter, req *http.Request, ps PathParams) error {
+		if len(ps) != 2 {
+			return errors.Wrapf(ErrNotFoundHandler, "path=%s, handler=%T", req.URL.Path, h)
+		}
+		a, err := decodeA(ps[0].Value)
+		if err != nil {
+			return errors.Wrapf(err, "path=%s", req.URL.Path)
+		}
+		b, err := decodeB(ps[1].Value)
+		if err != nil {
+			return errors.Wrapf(err, "path=%s", req.URL.Path)
+		}
+		h(w, req, a, b)
+		return nil
+	}
+	return r.handlePlan(method, path, h, plan)
+}
+
+// Get1 register handler via GET, see Handle1
+func Get1[A any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A)) *Route {
+	route, err := Handle1(r, "GET", path, h)
+	return r.registeredRoute("GET", path, h, route, err)
+}
+
+// Get2 register handler via GET, see Handle2
+func Get2[A, B any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A, B)) *Route {
+	route, err := Handle2(r, "GET", path, h)
+	return r.registeredRoute("GET", path, h, route, err)
+}
+
+// Post1 register handler via POST, see Handle1
+func Post1[A any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A)) *Route {
+	route, err := Handle1(r, "POST", path, h)
+	return r.registeredRoute("POST", path, h, route, err)
+}
+
+// Post2 register handler via POST, see Handle2
+func Post2[A, B any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A, B)) *Route {
+	route, err := Handle2(r, "POST", path, h)
+	return r.registeredRoute("POST", path, h, route, err)
+}
+
+// Put1 register handler via PUT, see Handle1
+func Put1[A any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A)) *Route {
+	route, err := Handle1(r, "PUT", path, h)
+	return r.registeredRoute("PUT", path, h, route, err)
+}
+
+// Put2 register handler via PUT, see Handle2
+func Put2[A, B any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A, B)) *Route {
+	route, err := Handle2(r, "PUT", path, h)
+	return r.registeredRoute("PUT", path, h, route, err)
+}
+
+// Delete1 register handler via DELETE, see Handle1
+func Delete1[A any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A)) *Route {
+	route, err := Handle1(r, "DELETE", path, h)
+	return r.registeredRoute("DELETE", path, h, route, err)
+}
+
+// Delete2 register handler via DELETE, see Handle2
+func Delete2[A, B any](r *Router, path string, h func(http.ResponseWriter, *http.Request, A, B)) *Route {
+	route, err := Handle2(r, "DELETE", path, h)
+	return r.registeredRoute("DELETE", path, h, route, err)
+}
+
+// typedParams returns the path parameter names and the declared types, returns error when the number is not n
+func typedParams(path string, n int) ([]string, []string, error) {
+	names, err := paramNames(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(names) != n {
+		return nil, nil, errors.Wrapf(ErrInvalidHandler, "number of params mismatch. path has %d params %v, handler has %d params", len(names), names, n)
+	}
+	types, err := paramSegmentTypes(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return names, types, nil
+}
+
+// typedParamDecoder returns the decoder of the path parameter to T, typ is the declared type of the segment.
+// string and int are decoded without reflection unless registered Converter,
+// the others are decoded via the same conversion as the reflect path.
+func typedParamDecoder[T any](name, typ string, r *Router) (func(raw string) (T, error), error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if err := validateSegmentType(typ, t, r.registered); err != nil {
+		return nil, errors.Wrapf(err, "param=%s", name)
+	}
+	lookup := segmentLookup(typ, r.converter)
+	if err := validateParamType(t, lookup); err != nil {
+		return nil, errors.Wrapf(err, "param=%s", name)
+	}
+
+	var zero T
+	if !r.registered(t) {
+		switch any(zero).(type) {
+		case string:
+			return func(raw string) (T, error) {
+				return any(raw).(T), nil
+			}, nil
+		case int:
+			return func(raw string) (T, error) {
+				n, err := strconv.ParseInt(raw, 10, 0)
+				if err != nil {
+					return zero, &ParamError{Name: name, Raw: raw, Err: err}
+				}
+				return any(int(n)).(T), nil
+			}, nil
+		}
+	}
+
+	decode := newParamDecoder(name, t, lookup)
+	return func(raw string) (T, error) {
+		v, err := decode(raw)
+		if err != nil {
+			return zero, err
+		}
+		return v.Interface().(T), nil
+	}, nil
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestServeHTTPWithTyped(t *testing.T) {
+	r := NewRouter()
+	r.RegisterConverter(reflect.TypeOf(dummyUserID{}), func(raw string) (reflect.Value, error) {
+		return reflect.ValueOf(dummyUserID{id: len(raw)}), nil
+	})
+	Get1(r, "/user/:id", func(w http.ResponseWriter, req *http.Request, id int) {
+		fmt.Fprintf(w, "id=%d", id)
+	})
+	Get2(r, "/user/:id/:name", func(w http.ResponseWriter, req *http.Request, id int, name string) {
+		fmt.Fprintf(w, "id=%d name=%s", id, name)
+	})
+	Post1(r, "/user/:id", func(w http.ResponseWriter, req *http.Request, id dummyUserID) {
+		fmt.Fprintf(w, "post id=%d", id.id)
+	})
+	Put2(r, "/item/:price/:valid", func(w http.ResponseWriter, req *http.Request, price float64, valid bool) {
+		fmt.Fprintf(w, "price=%.1f valid=%t route=%s", price, valid, CurrentRoute(req).GetPath())
+	})
+	Delete1(r, "/validation/:v", func(w http.ResponseWriter, req *http.Request, v *dummyValidationParam) {
+		fmt.Fprint(w, "validation")
+	})
+	r.Get("/func/:id/:name", HandlerFunc(func(w http.ResponseWriter, req *http.Request, ps PathParams) {
+		fmt.Fprintf(w, "id=%s name=%s", ps.ByName("id"), ps.ByName("name"))
+	}))
+	r.Get("/params/:id", func(w http.ResponseWriter, ps PathParams) {
+		fmt.Fprintf(w, "params=%v", ps)
+	})
+
+	cases := []struct {
+		inputMethod string
+		inputPath   string
+		expectCode  int
+		expectBody  string
+	}{
+		{"GET", "/user/10", 200, "id=10"},
+		{"GET", "/user/10/foo", 200, "id=10 name=foo"},
+		{"POST", "/user/abc", 200, "post id=3"},
+		{"PUT", "/item/1.5/true", 200, "price=1.5 valid=true route=/item/:price/:valid"},
+		{"DELETE", "/validation/100", 200, "validation"},
+		{"GET", "/func/10/foo", 200, "id=10 name=foo"},
+		{"GET", "/params/10", 200, "params=[{id 10}]"},
+		{"GET", "/user/foo", 400, "invalid param: id\n"},
+		{"PUT", "/item/1.5/foo", 400, "invalid param: valid\n"},
+		{"DELETE", "/validation/1", 400, "invalid param: v\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.inputMethod, c.inputPath, nil))
+		if w.Code != c.expectCode {
+			t.Errorf("#%d: want status code:%d, got status code:%d", i, c.expectCode, w.Code)
+		}
+		if body := w.Body.String(); body != c.expectBody {
+			t.Errorf("#%d: want body:%q, got body:%q", i, c.expectBody, body)
+		}
+	}
+}
+
+func TestHandleTypedError(t *testing.T) {
+	type unsupported struct{}
+
+	r := NewRouter()
+	cases := []struct {
+		register func() error
+		expect   error
+	}{
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/:id", func(w http.ResponseWriter, req *http.Request, id int) {})
+				return err
+			},
+			nil,
+		},
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/:id/:name", func(w http.ResponseWriter, req *http.Request, id int) {})
+				return err
+			},
+			ErrInvalidHandler,
+		},
+		{
+			func() error {
+				_, err := Handle2(r, "GET", "/foo/:id", func(w http.ResponseWriter, req *http.Request, id int, name string) {})
+				return err
+			},
+			ErrInvalidHandler,
+		},
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/bar/:id", func(w http.ResponseWriter, req *http.Request, v unsupported) {})
+				return err
+			},
+			ErrInvalidHandler,
+		},
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/:name", func(w http.ResponseWriter, req *http.Request, name string) {})
+				return err
+			},
+			ErrAlreadyPathRegistered,
+		},
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/items/:id:int", func(w http.ResponseWriter, req *http.Request, id int64) {})
+				return err
+			},
+			nil,
+		},
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/items/:slug:alpha", func(w http.ResponseWriter, req *http.Request, slug int) {})
+				return err
+			},
+			ErrInvalidHandler,
+		},
+		{
+			func() error {
+				_, err := Handle1(r, "GET", "/orders/:id:int", func(w http.ResponseWriter, req *http.Request, id uint) {})
+				return err
+			},
+			ErrInvalidHandler,
+		},
+	}
+	for i, c := range cases {
+		if err := c.register(); errors.Cause(err) != c.expect {
+			t.Errorf("#%d: want error:%v, got error:%v", i, c.expect, err)
+		}
+	}
+}
+
+func BenchmarkServeHTTPTyped(b *testing.B) {
+	r := NewRouter()
+	Get2(r, "/user/:id/:name", func(w http.ResponseWriter, req *http.Request, id int, name string) {})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/user/10/foo", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}